@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DefaultBatchConcurrency is the number of calls executed concurrently
+// by a batch executor created with a non-positive concurrency.
+const DefaultBatchConcurrency = 16
+
+// BatchCall is a single call executed by the batch executor.
+type BatchCall struct {
+	// TServer is the tablet server UUID or host:port the call is executed against.
+	// An empty tablet server executes the call against the master leader.
+	TServer  string
+	Payload  protoreflect.ProtoMessage
+	Response protoreflect.ProtoMessage
+	Options  []CallOption
+}
+
+// BatchResult is the result of a batch call.
+type BatchResult struct {
+	Call *BatchCall
+	Err  error
+}
+
+// BatchExecutor executes batches of calls with bounded concurrency.
+type BatchExecutor struct {
+	masterClient  YBClient
+	tserverClient YBTServerClient
+	concurrency   int
+}
+
+// NewBatchExecutor returns a batch executor executing master calls
+// with the master client, at most concurrency calls at a time.
+func NewBatchExecutor(masterClient YBClient, concurrency int) *BatchExecutor {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	return &BatchExecutor{
+		masterClient: masterClient,
+		concurrency:  concurrency,
+	}
+}
+
+// WithTServerClient configures the client used for calls with a tablet server.
+func (e *BatchExecutor) WithTServerClient(tserverClient YBTServerClient) *BatchExecutor {
+	e.tserverClient = tserverClient
+	return e
+}
+
+// Execute executes the calls and returns the results in the order of the calls.
+// A failed call does not stop the batch. Calls not started before the context
+// is done fail with the context error.
+func (e *BatchExecutor) Execute(ctx context.Context, calls ...*BatchCall) []*BatchResult {
+	results := make([]*BatchResult, len(calls))
+	slots := make(chan struct{}, e.concurrency)
+	wg := &sync.WaitGroup{}
+	for i, call := range calls {
+		results[i] = &BatchResult{Call: call}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(result *BatchResult) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			result.Err = e.execute(ctx, result.Call)
+		}(results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+func (e *BatchExecutor) execute(ctx context.Context, call *BatchCall) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if call.TServer != "" {
+		if e.tserverClient == nil {
+			return fmt.Errorf("no tablet server client configured for call to tablet server %s", call.TServer)
+		}
+		return e.tserverClient.ExecuteContext(ctx, call.TServer, call.Payload, call.Response, call.Options...)
+	}
+	if e.masterClient == nil {
+		return fmt.Errorf("no master client configured")
+	}
+	return e.masterClient.ExecuteContext(ctx, call.Payload, call.Response, call.Options...)
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteAsync(t *testing.T) {
+
+	chanRelease := make(chan struct{})
+	address := testMaster(t, func(request *testRequest) {
+		<-chanRelease
+		request.respond(&ybApi.ListMastersResponsePB{
+			Masters: []*ybApi.ServerEntryPB{testServerEntry(t, "127.0.0.1:7100")},
+		})
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{address},
+		OpTimeout:      time.Second,
+	})
+
+	t.Run("it=completes the future when the call finishes", func(tt *testing.T) {
+		response := &ybApi.ListMastersResponsePB{}
+		future := c.ExecuteAsync(context.Background(), &ybApi.ListMastersRequestPB{}, response)
+		select {
+		case <-future.Done():
+			tt.Fatal("expected the future to wait for the response")
+		case <-time.After(50 * time.Millisecond):
+		}
+		chanRelease <- struct{}{}
+		assert.Nil(tt, future.Wait(context.Background()))
+		assert.Equal(tt, response, future.Response())
+		assert.Equal(tt, 1, len(response.GetMasters()))
+	})
+
+	t.Run("it=returns the wait context error", func(tt *testing.T) {
+		future := c.ExecuteAsync(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.Equal(tt, context.DeadlineExceeded, future.Wait(ctx))
+		chanRelease <- struct{}{}
+		assert.Nil(tt, future.Wait(context.Background()))
+	})
+
+	t.Run("it=returns the call error", func(tt *testing.T) {
+		future := c.ExecuteAsync(context.Background(), &ybApi.ListMastersResponsePB{}, &ybApi.ListMastersResponsePB{})
+		assert.IsType(tt, &clientErrors.ProtoServiceError{}, future.Wait(context.Background()))
+	})
+
+}
+
+func TestBatchExecutor(t *testing.T) {
+
+	var current, max int32
+	address := testMaster(t, func(request *testRequest) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		<-time.After(20 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		payload := &ybApi.ListTablesRequestPB{}
+		utils.DeserializeProto(request.payload, payload)
+		if payload.GetNameFilter() == "fail" {
+			request.respondBytes([]byte{0xff, 0xff, 0xff})
+			return
+		}
+		request.respond(&ybApi.ListTablesResponsePB{
+			Tables: []*ybApi.ListTablesResponsePB_TableInfo{
+				{Id: []byte(payload.GetNameFilter()), Name: utils.PString(payload.GetNameFilter())},
+			},
+		})
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort:    []string{address},
+		OpTimeout:         time.Second,
+		MaxExecuteRetries: configs.NoExecuteRetry,
+	})
+
+	t.Run("it=executes calls with bounded concurrency", func(tt *testing.T) {
+		atomic.StoreInt32(&max, 0)
+		calls := []*BatchCall{}
+		for i := 0; i < 12; i = i + 1 {
+			calls = append(calls, &BatchCall{
+				Payload:  &ybApi.ListTablesRequestPB{NameFilter: utils.PString(fmt.Sprintf("table-%d", i))},
+				Response: &ybApi.ListTablesResponsePB{},
+			})
+		}
+		results := NewBatchExecutor(c, 3).Execute(context.Background(), calls...)
+		if assert.Equal(tt, len(calls), len(results)) {
+			for i, result := range results {
+				assert.Nil(tt, result.Err)
+				assert.Equal(tt, calls[i], result.Call)
+				assert.Equal(tt, fmt.Sprintf("table-%d", i), result.Call.Response.(*ybApi.ListTablesResponsePB).GetTables()[0].GetName())
+			}
+		}
+		assert.LessOrEqual(tt, atomic.LoadInt32(&max), int32(3))
+		assert.Greater(tt, atomic.LoadInt32(&max), int32(1))
+	})
+
+	t.Run("it=returns errors per call", func(tt *testing.T) {
+		results := NewBatchExecutor(c, 0).Execute(context.Background(),
+			&BatchCall{
+				Payload:  &ybApi.ListTablesRequestPB{NameFilter: utils.PString("ok")},
+				Response: &ybApi.ListTablesResponsePB{},
+			},
+			&BatchCall{
+				Payload:  &ybApi.ListTablesRequestPB{NameFilter: utils.PString("fail")},
+				Response: &ybApi.ListTablesResponsePB{},
+			},
+			&BatchCall{
+				TServer:  "tserver-1",
+				Payload:  &ybApi.ListTabletsRequestPB{},
+				Response: &ybApi.ListTabletsResponsePB{},
+			})
+		assert.Nil(tt, results[0].Err)
+		assert.IsType(tt, &clientErrors.UnprocessableResponseError{}, results[1].Err)
+		assert.Error(tt, results[2].Err)
+	})
+
+	t.Run("it=fails calls not started when the context is done", func(tt *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results := NewBatchExecutor(c, 1).Execute(ctx, &BatchCall{
+			Payload:  &ybApi.ListTablesRequestPB{},
+			Response: &ybApi.ListTablesResponsePB{},
+		})
+		assert.Equal(tt, context.Canceled, results[0].Err)
+	})
+
+}
+
+func TestMaxInFlightRequests(t *testing.T) {
+
+	chanRelease := make(chan struct{})
+	address := testMaster(t, func(request *testRequest) {
+		<-chanRelease
+		request.respond(&ybApi.ListMastersResponsePB{})
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort:      []string{address},
+		OpTimeout:           time.Second,
+		MaxExecuteRetries:   configs.NoExecuteRetry,
+		MaxInFlightRequests: 1,
+	})
+
+	t.Run("it=waits for a free slot", func(tt *testing.T) {
+		first := c.ExecuteAsync(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		<-time.After(50 * time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.Error(tt, c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		second := c.ExecuteAsync(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		chanRelease <- struct{}{}
+		assert.Nil(tt, first.Wait(context.Background()))
+		chanRelease <- struct{}{}
+		assert.Nil(tt, second.Wait(context.Background()))
+	})
+
+}
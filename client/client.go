@@ -1,18 +1,20 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/radekg/yugabyte-db-go-client/configs"
 	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
 	"github.com/radekg/yugabyte-db-go-client/metrics"
-	"google.golang.org/protobuf/proto"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
 	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
@@ -20,19 +22,92 @@ import (
 
 // YBClient is a high-level YugabyteDB client.
 type YBClient interface {
-	// Close closes the connected client.
+	// Close closes the client immediately, calls in progress fail.
+	// Closing a closed client is a no-op.
 	Close() error
 	// Connects the client.
 	Connect() error
+	// MasterAddresses returns the master addresses used when connecting.
+	// With master discovery enabled, these are the configured master addresses
+	// merged with the addresses discovered from the connected master.
+	MasterAddresses() []string
+	// ServerVersion returns the version of the connected master leader.
+	// The version is detected only if version profiles are configured.
+	ServerVersion() (ServerVersion, bool)
+	// State returns the current connection state.
+	State() ConnectionState
+	// WatchState returns a channel receiving the current connection state
+	// followed by every state change. The channel is closed when the context is done.
+	// A watcher not keeping up misses intermediate states, the latest state is always delivered.
+	WatchState(ctx context.Context) <-chan ConnectionState
 	// Execute executes the payload against the service
 	// and populates the response with the response data.
+	// Execute is safe for concurrent use, concurrent calls are pipelined
+	// over a single connection.
 	Execute(payload, response protoreflect.ProtoMessage) error
+	// ExecuteContext executes the payload against the service
+	// and populates the response with the response data.
+	// The remaining context deadline is sent as the call timeout.
+	// Cancelling the context interrupts the call, retries and reconnects.
+	// Call options apply to this call only.
+	ExecuteContext(ctx context.Context, payload, response protoreflect.ProtoMessage, opts ...CallOption) error
+	// ExecuteAsync executes the payload in the background like ExecuteContext
+	// and returns immediately. The returned future completes when the call finishes.
+	ExecuteAsync(ctx context.Context, payload, response protoreflect.ProtoMessage, opts ...CallOption) *Future
+	// ExecuteJSON executes the method of the service with the JSON payload
+	// and returns the response as JSON. The request and response messages are resolved
+	// from the registered file descriptors, the service is the name used in the request header
+	// or the full name of the protobuf service. Methods of the file descriptor sets
+	// loaded into the service registry can be executed.
+	ExecuteJSON(ctx context.Context, service, method string, jsonPayload []byte, opts ...CallOption) ([]byte, error)
+	// ExecuteMethod executes the payload like ExecuteContext against the service and method
+	// of the service info instead of the ones registered for the payload type,
+	// for example when the input type is shared by multiple methods.
+	// The payload and the response must be the input and the output types of the method,
+	// errors.MethodTypeMismatchError is returned otherwise. A nil service info executes the registered method.
+	ExecuteMethod(ctx context.Context, svcInfo ServiceInfo, payload, response protoreflect.ProtoMessage, opts ...CallOption) error
+	// Shutdown stops accepting new calls, waits for the calls in progress
+	// until the context is done and closes the client. Reconnects in progress are cancelled.
+	// Calls executed after the shutdown has started fail with errors.ErrClosed.
+	// Returns the context error if the calls in progress did not finish in time.
+	Shutdown(ctx context.Context) error
+	// Registers a hook called when the client connects to a different master leader.
+	// The old leader is empty when the client connects for the first time.
+	// Hooks are called synchronously and must not block.
+	OnLeaderChange(hook func(oldLeader, newLeader string)) YBClient
+	// Registers a hook called after every reconnect attempt with the leader address
+	// or the reconnect error. Hooks are called synchronously and must not block.
+	OnReconnect(hook func(leaderAddress string, err error)) YBClient
+	// Registers a hook called when the client is closed.
+	OnClose(hook func()) YBClient
+	// Allows configuring the dialer used to connect to the servers.
+	// Defaults to a TCP dialer with the configured dial timeout and keepalive.
+	WithDialer(dialer Dialer) YBClient
+	// Allows adding interceptors wrapping every call executed by the client,
+	// including all retries and reconnects. Interceptors are called in the order they were added.
+	WithInterceptors(interceptors ...UnaryInterceptor) YBClient
 	// Allows configuring the logger used by the client.
 	// Uses go-hclog. Users can provide integrate with any logging
 	// framework using https://pkg.go.dev/github.com/hashicorp/go-hclog#InterceptLogger.
 	WithLogger(logger hclog.Logger) YBClient
 	// Allows providing custom implementation of the metrics callback.
 	WithMetricsCallback(callback metrics.Callback) YBClient
+	// Allows providing custom retry policy used for all calls.
+	WithRetryPolicy(policy RetryPolicy) YBClient
+	// Allows configuring the service registry, for example a registry
+	// with additional file descriptor sets loaded. The registry may be shared by clients.
+	// Defaults to a registry with all YugabyteDB service definitions loaded.
+	WithServiceRegistry(registry ServiceRegistry) YBClient
+	// Allows configuring the tracer provider. Every call is traced with a span
+	// parented by the span from the call context, every attempt is traced with a child span.
+	// Attempts are traced only by connections established after the tracer provider is configured.
+	WithTracerProvider(provider trace.TracerProvider) YBClient
+	// Allows configuring the version profiles for clusters running different YugabyteDB releases.
+	// The client detects the version of every master it connects to and uses
+	// the service registry of the matching profile, the configured service registry
+	// is used if no profile matches or the version cannot be detected.
+	// The profiles are added to the built-in ones and override those of the same minimum version.
+	WithVersionProfiles(profiles ...VersionProfile) YBClient
 }
 
 var (
@@ -44,24 +119,68 @@ var (
 	errNotReconnected    = fmt.Errorf(clientErrors.ErrorMessageReconnectFailed)
 )
 
+// leaderHintTimeout bounds the leader hint query against the failed master,
+// a master which stopped responding must not delay finding the leader.
+const leaderHintTimeout = time.Second
+
 type defaultYBClient struct {
-	config          *configs.YBClientConfig
-	connectedClient YBConnectedClient
-	isConnecting    bool
-	isConnected     bool
-	lock            *sync.Mutex
-	logger          hclog.Logger
-	metricsCallback metrics.Callback
+	calls             *sync.WaitGroup
+	callsInFlight     int32
+	chanClosed        chan struct{}
+	chanShutdown      chan struct{}
+	chanStopDiscovery chan struct{}
+	config            *configs.YBClientConfig
+	connectedClient   YBConnectedClient
+	dialer            Dialer
+	discoveredMasters []string
+	hooks             connectionHooks
+	interceptors      []UnaryInterceptor
+	leaderAddress     string
+	reconnecting      *reconnectOp
+	replicas          *masterReplicas
+	lock              *sync.Mutex
+	logger            hclog.Logger
+	metricsCallback   metrics.Callback
+	retryPolicy       RetryPolicy
+	state             ConnectionState
+	stateWatchers     *stateWatchers
+	svcRegistry       ServiceRegistry
+	tracerProvider    trace.TracerProvider
+	versionProfiles   VersionProfiles
 }
 
 // NewYBClient constructs a new instance of the high-level YugabyteDB client.
 func NewYBClient(config *configs.YBClientConfig) YBClient {
+	config = config.WithDefaults()
 	return &defaultYBClient{
-		config:          config.WithDefaults(),
+		calls:           &sync.WaitGroup{},
+		chanClosed:      make(chan struct{}),
+		chanShutdown:    make(chan struct{}),
+		config:          config,
+		dialer:          NewTCPDialer(config.DialTimeout, config.KeepAlive),
 		lock:            &sync.Mutex{},
 		logger:          hclog.Default(),
 		metricsCallback: metrics.Noop(),
+		replicas:        newMasterReplicas(),
+		retryPolicy:     NewExponentialBackoffRetryPolicy(config),
+		state:           StateIdle,
+		stateWatchers:   newStateWatchers(),
+		svcRegistry:     NewLoadedServiceRegistry(),
+		tracerProvider:  trace.NewNoopTracerProvider(),
+		versionProfiles: DefaultVersionProfiles(),
+	}
+}
+
+func (c *defaultYBClient) WithInterceptors(interceptors ...UnaryInterceptor) YBClient {
+	c.interceptors = append(c.interceptors, interceptors...)
+	return c
+}
+
+func (c *defaultYBClient) WithDialer(dialer Dialer) YBClient {
+	if dialer != nil {
+		c.dialer = dialer
 	}
+	return c
 }
 
 func (c *defaultYBClient) WithLogger(logger hclog.Logger) YBClient {
@@ -74,313 +193,686 @@ func (c *defaultYBClient) WithMetricsCallback(callback metrics.Callback) YBClien
 	return c
 }
 
-func (c *defaultYBClient) Close() error {
+func (c *defaultYBClient) WithRetryPolicy(policy RetryPolicy) YBClient {
+	if policy != nil {
+		c.retryPolicy = policy
+	}
+	return c
+}
+
+func (c *defaultYBClient) WithServiceRegistry(registry ServiceRegistry) YBClient {
+	if registry != nil {
+		c.svcRegistry = registry
+	}
+	return c
+}
+
+func (c *defaultYBClient) WithTracerProvider(provider trace.TracerProvider) YBClient {
+	if provider != nil {
+		c.tracerProvider = provider
+	}
+	return c
+}
+
+func (c *defaultYBClient) WithVersionProfiles(profiles ...VersionProfile) YBClient {
+	c.versionProfiles = append(c.versionProfiles, profiles...)
+	return c
+}
+
+func (c *defaultYBClient) OnLeaderChange(hook func(oldLeader, newLeader string)) YBClient {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if c.connectedClient == nil {
-		return errNoClient
-	}
-	closeError := c.closeUnsafe()
-	c.isConnected = false
-	c.connectedClient = nil
-	return closeError
+	c.hooks.onLeaderChange = append(c.hooks.onLeaderChange, hook)
+	return c
 }
 
-func (c *defaultYBClient) Connect() error {
+func (c *defaultYBClient) OnReconnect(hook func(leaderAddress string, err error)) YBClient {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if c.isConnecting {
-		return errConnecting
-	}
-	if c.isConnected || c.connectedClient != nil {
-		return errConnected
-	}
-	return c.connectUnsafe()
+	c.hooks.onReconnect = append(c.hooks.onReconnect, hook)
+	return c
 }
 
-func (c *defaultYBClient) Execute(payload, response protoreflect.ProtoMessage) error {
+func (c *defaultYBClient) OnClose(hook func()) YBClient {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	c.hooks.onClose = append(c.hooks.onClose, hook)
+	return c
+}
 
-	if !c.isConnected {
-		return errNotConnected
-	}
+func (c *defaultYBClient) State() ConnectionState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.state
+}
 
+func (c *defaultYBClient) ServerVersion() (ServerVersion, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	if c.connectedClient == nil {
-		return errNoClient
+		return ServerVersion{}, false
 	}
+	return c.connectedClient.ServerVersion()
+}
 
-	currentAttempt := int32(1)
-
-	for {
+// serviceRegistry returns the service registry of the profile
+// selected for the version of the connected master leader.
+func (c *defaultYBClient) serviceRegistry() ServiceRegistry {
+	version, ok := c.ServerVersion()
+	if !ok {
+		return c.svcRegistry
+	}
+	return c.versionProfiles.registry(version, c.svcRegistry)
+}
 
-		executeErr := c.connectedClient.Execute(payload, response)
-
-		// the response might have an error in it, check if this is a response returning ybApi.MasterErrorPB
-		if tResponse, ok := response.(clientErrors.AbstractMasterErrorResponse); ok {
-			// was there an error in that response?
-			if masterError := clientErrors.NewMasterError(tResponse.GetError()); masterError != nil {
-				// we know it was not nil because masterError was not nil
-				responseError := tResponse.GetError()
-				if responseError.Code != nil {
-					responseErrorCode := *responseError.Code
-					if int32(responseErrorCode.Number()) == int32(ybApi.MasterErrorPB_NOT_THE_LEADER.Number()) {
-						c.logger.Warn("execute: response with NOT_THE_LEADER master status code, reconnect", "reason", masterError)
-						executeErr = &clientErrors.RequiresReconnectError{
-							Cause: masterError,
-						}
-					}
-				}
-			}
-		}
+func (c *defaultYBClient) WatchState(ctx context.Context) <-chan ConnectionState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stateWatchers.watch(ctx, c.state)
+}
 
-		if executeErr == nil {
-			return nil
-		}
+func (c *defaultYBClient) Close() error {
+	// do not wait for the calls in progress:
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	_, closeError := c.shutdown(ctx)
+	return closeError
+}
 
-		// Previous execute call could have resulted
-		// in an error response.
-		// Because we are reusing response object, we have to reset it!
-		proto.Reset(response)
+func (c *defaultYBClient) Shutdown(ctx context.Context) error {
+	drainError, closeError := c.shutdown(ctx)
+	if drainError != nil {
+		return drainError
+	}
+	return closeError
+}
 
-		if c.config.MaxExecuteRetries <= configs.NoExecuteRetry {
-			reportErr := executeErr
-			if tReconnectError, ok := executeErr.(*clientErrors.RequiresReconnectError); ok {
-				reportErr = tReconnectError.Cause
-			}
-			c.logger.Error("execute: retry disabled, not retrying", "reason", reportErr)
-			return reportErr
+// shutdown returns the context error if the calls in progress did not finish
+// before the context was done and the error returned when closing the connected client.
+func (c *defaultYBClient) shutdown(ctx context.Context) (drainError, closeError error) {
+	c.lock.Lock()
+	switch c.state {
+	case StateClosed:
+		c.lock.Unlock()
+		return nil, nil
+	case StateShuttingDown:
+		// another shutdown in progress:
+		c.lock.Unlock()
+		select {
+		case <-c.chanClosed:
+			return nil, nil
+		case <-ctx.Done():
+			return ctx.Err(), nil
 		}
-
-		if currentAttempt > c.config.MaxExecuteRetries {
-			reportErr := executeErr
-			if tReconnectError, ok := executeErr.(*clientErrors.RequiresReconnectError); ok {
-				reportErr = tReconnectError.Cause
+	}
+	c.stopDiscoveryUnsafe()
+	close(c.chanShutdown)
+	c.setStateUnsafe(StateShuttingDown)
+	c.lock.Unlock()
+
+	// no new calls are accepted, the wait group can be waited on:
+	chanDrained := make(chan struct{})
+	go func() {
+		c.calls.Wait()
+		close(chanDrained)
+	}()
+	if atomic.LoadInt32(&c.callsInFlight) > 0 {
+		select {
+		case <-chanDrained:
+		case <-ctx.Done():
+			// calls could have finished while the context was done:
+			if inFlight := atomic.LoadInt32(&c.callsInFlight); inFlight > 0 {
+				drainError = ctx.Err()
+				c.logger.Warn("closing with calls in progress", "in-flight", inFlight, "reason", drainError)
 			}
-			c.logger.Error("execute: failed for a maximum number of allowed attempts, giving up", "reason", reportErr)
-			return reportErr
 		}
+	}
 
-		if errors.Is(executeErr, syscall.EPIPE) {
-			// broken pipe qualifies for immediate retry:
-			executeErr = &clientErrors.RequiresReconnectError{
-				Cause: executeErr,
-			}
-		} else if _, ok := executeErr.(*clientErrors.SendError); ok {
-			// the client was connected but is no longer able to
-			// send data to the server, this qualifies
-			// for reconnect
-			executeErr = &clientErrors.RequiresReconnectError{
-				Cause: executeErr,
-			}
-		} else if tError, ok := executeErr.(*clientErrors.ReceiveError); ok {
-
-			// could not read payload from the server,
-			// if not requires reconnect, just retry...
-			if !tError.RequiresReconnect() {
-				currentAttempt = currentAttempt + 1
-				<-time.After(c.config.RetryInterval)
-				continue
-
-			}
-			// otherwise reconnect and retry...
-			executeErr = &clientErrors.RequiresReconnectError{
-				Cause: executeErr,
-			}
-
-		} else if _, ok := executeErr.(*clientErrors.UnprocessableResponseError); ok {
-			// complete payload has been read from the server
-			// but payload could not be deserialized as protobuf,
-			// this qualifies for immediate retry:
-			currentAttempt = currentAttempt + 1
-			<-time.After(c.config.RetryInterval)
-			continue
+	c.lock.Lock()
+	if c.connectedClient != nil {
+		closeError = c.closeUnsafe()
+		// a failed reconnect closes the connection before replacing the client:
+		if errors.Is(closeError, net.ErrClosed) {
+			closeError = nil
 		}
+		c.connectedClient = nil
+	}
+	c.replicas.close()
+	c.setStateUnsafe(StateClosed)
+	close(c.chanClosed)
+	hooks := c.hooks.onClose
+	c.lock.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+	return drainError, closeError
+}
 
-		if tReconnectError, ok := executeErr.(*clientErrors.RequiresReconnectError); ok {
-
-			if c.config.MaxReconnectAttempts <= configs.NoReconnectAttempts {
-				c.logger.Error("execute: not reconnecting after error, max reconnect attempts not set",
-					"reason", tReconnectError.Cause)
-				return tReconnectError.Cause
-			}
-
-			c.logger.Debug("execute: attempting reconnect due to an error",
-				"reason", tReconnectError.Cause)
-
-			// reconnect:
-			currentReconnectAttempt := int32(1)
-			reconnected := false
-			for {
+func (c *defaultYBClient) Connect() error {
+	c.lock.Lock()
+	if c.isClosedUnsafe() {
+		c.lock.Unlock()
+		return clientErrors.ErrClosed
+	}
+	if c.state == StateConnecting {
+		c.lock.Unlock()
+		return errConnecting
+	}
+	if c.connectedClient != nil {
+		c.lock.Unlock()
+		return errConnected
+	}
+	previousState := c.state
+	previousLeader := c.leaderAddress
+	addresses := c.masterAddressesUnsafe()
+	c.setStateUnsafe(StateConnecting)
+	c.lock.Unlock()
 
-				c.metricsCallback.ClientReconnectAttempt()
+	// the lock is not held while looking for the leader so the state can be observed:
+	leaderAddress, connectedClient, err := c.findLeader(context.Background(), []string{previousLeader}, addresses)
 
-				reconnectErr := c.reconnect()
+	c.lock.Lock()
+	if c.isClosedUnsafe() {
+		// closed while connecting:
+		c.lock.Unlock()
+		if err == nil {
+			connectedClient.Close()
+		}
+		return clientErrors.ErrClosed
+	}
+	if err != nil {
+		c.setStateUnsafe(previousState)
+		c.lock.Unlock()
+		return err
+	}
+	c.setLeaderUnsafe(leaderAddress, connectedClient)
+	c.startDiscoveryUnsafe()
+	hooks := c.hooks.onLeaderChange
+	c.lock.Unlock()
+	runLeaderChangeHooks(hooks, previousLeader, leaderAddress)
+	return nil
+}
 
-				if reconnectErr == nil {
-					c.metricsCallback.ClientReconnectSuccess()
-					reconnected = true
-					break
-				}
+func (c *defaultYBClient) Execute(payload, response protoreflect.ProtoMessage) error {
+	return c.ExecuteContext(context.Background(), payload, response)
+}
 
-				c.metricsCallback.ClientReconnectFailure()
+func (c *defaultYBClient) ExecuteAsync(ctx context.Context, payload, response protoreflect.ProtoMessage, opts ...CallOption) *Future {
+	return newFuture(response, func() error {
+		return c.ExecuteContext(ctx, payload, response, opts...)
+	})
+}
 
-				if currentReconnectAttempt == c.config.MaxReconnectAttempts {
-					break
-				}
+func (c *defaultYBClient) ExecuteJSON(ctx context.Context, service, method string, jsonPayload []byte, opts ...CallOption) ([]byte, error) {
+	call, err := newDynamicCall(c.serviceRegistry(), service, method, jsonPayload)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ExecuteContext(ctx, call.payload, call.response, call.options(opts)...); err != nil {
+		return nil, err
+	}
+	return call.responseJSON()
+}
 
-				c.logger.Error("execute: failed reconnect",
-					"attempt", currentReconnectAttempt,
-					"max-attempts", c.config.MaxReconnectAttempts,
-					"reason", reconnectErr)
+func (c *defaultYBClient) ExecuteMethod(ctx context.Context, svcInfo ServiceInfo, payload, response protoreflect.ProtoMessage, opts ...CallOption) error {
+	if svcInfo != nil {
+		if err := checkMethodTypes(c.serviceRegistry(), svcInfo, payload, response); err != nil {
+			return err
+		}
+	}
+	return c.ExecuteContext(ctx, payload, response, append(append([]CallOption{}, opts...), callWithServiceInfo(svcInfo))...)
+}
 
-				currentReconnectAttempt = currentReconnectAttempt + 1
-				<-time.After(c.config.ReconnectRetryInterval)
+func (c *defaultYBClient) ExecuteContext(ctx context.Context, payload, response protoreflect.ProtoMessage, opts ...CallOption) error {
+	if err := c.beginCall(); err != nil {
+		return err
+	}
+	defer c.endCall()
+	options := newCallOptions(opts...)
+	svcInfo := resolveServiceInfo(c.serviceRegistry(), payload, options)
+	if svcInfo == nil {
+		return &clientErrors.ProtoServiceError{
+			ProtoType: payload.ProtoReflect().Descriptor().FullName(),
+		}
+	}
+	retryPolicy := c.retryPolicy
+	if options.retryPolicy != nil {
+		retryPolicy = options.retryPolicy
+	}
+	executor := &retryingExecutor{
+		logger:          c.logger,
+		metricsCallback: c.metricsCallback,
+		currentClient:   c.currentClient,
+		reconnect:       c.reconnect,
+		chanShutdown:    c.chanShutdown,
+	}
+	execute := func(ctx context.Context, payload, response protoreflect.ProtoMessage) error {
+		return executor.execute(ctx, retryPolicy, payload, response, opts...)
+	}
+	if IsReadOnlyMethod(svcInfo) && (options.readPreference != ReadPreferenceLeader || options.hedgingDelay > 0) {
+		leader := execute
+		execute = func(ctx context.Context, payload, response protoreflect.ProtoMessage) error {
+			return c.executeRead(ctx, options, payload, response, leader, opts)
+		}
+	}
+	ctx, span := startCallSpan(ctx, newTracer(c.tracerProvider), svcInfo)
+	err := chainUnaryInterceptors(c.interceptors, svcInfo, execute)(ctx, payload, response)
+	endCallSpan(span, response, err)
+	return err
+}
 
-			}
+// beginCall registers a call in progress.
+// Returns errors.ErrClosed if the client is closed or shutting down.
+func (c *defaultYBClient) beginCall() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.isClosedUnsafe() {
+		return clientErrors.ErrClosed
+	}
+	c.calls.Add(1)
+	atomic.AddInt32(&c.callsInFlight, 1)
+	return nil
+}
 
-			if !reconnected {
-				c.logger.Error("execute: failed reconnect consecutive maximum reconnect attempts",
-					"max-attempts", c.config.MaxReconnectAttempts,
-					"reason", tReconnectError.Cause)
-				return fmt.Errorf("%s: %s", errNotReconnected.Error(), tReconnectError.Cause.Error())
-			}
+// endCall unregisters a call registered with beginCall.
+func (c *defaultYBClient) endCall() {
+	atomic.AddInt32(&c.callsInFlight, -1)
+	c.calls.Done()
+}
 
-			// retry:
-			c.logger.Info("execute: client successfully reconnected")
-			currentAttempt = currentAttempt + 1
-			continue
+func (c *defaultYBClient) isClosedUnsafe() bool {
+	return c.state == StateShuttingDown || c.state == StateClosed
+}
 
-		} // reconnect handling / end
+func (c *defaultYBClient) closeUnsafe() error {
+	return c.connectedClient.Close()
+}
 
-		// in case of any other error, no recovery:
-		return executeErr
+func (c *defaultYBClient) setLeaderUnsafe(leaderAddress string, connectedClient YBConnectedClient) {
+	c.logger.Debug("Setting connected client...", "host-port", leaderAddress)
+	c.metricsCallback.ClientConnect()
+	c.connectedClient = connectedClient
+	c.leaderAddress = leaderAddress
+	// masters rejecting reads could have become followers serving reads:
+	c.replicas.resetRejected()
+	c.setStateUnsafe(StateReady)
+}
 
+// setStateUnsafe changes the state and notifies the state watchers.
+// Watchers are notified under the client lock so they observe the changes in order.
+func (c *defaultYBClient) setStateUnsafe(state ConnectionState) {
+	if c.state == state {
+		return
 	}
-
+	c.logger.Debug("connection state changed", "from", c.state.String(), "to", state.String())
+	c.state = state
+	c.stateWatchers.notify(state)
 }
 
-func (c *defaultYBClient) closeUnsafe() error {
-	return c.connectedClient.Close()
+// runLeaderChangeHooks calls the hooks if the leader has changed.
+func runLeaderChangeHooks(hooks []func(oldLeader, newLeader string), oldLeader, newLeader string) {
+	if oldLeader == newLeader {
+		return
+	}
+	for _, hook := range hooks {
+		hook(oldLeader, newLeader)
+	}
 }
 
-func (c *defaultYBClient) connectUnsafe() error {
+// findLeader finds the master leader and returns the connected client for the leader.
+// Preferred addresses are tried one by one first, if none of them is the leader,
+// all remaining addresses are queried concurrently.
+func (c *defaultYBClient) findLeader(ctx context.Context, preferred, addresses []string) (string, YBConnectedClient, error) {
 
 	tlsConfig, err := c.config.TLSConfig()
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
-	c.isConnecting = true
+	// a master of another cluster fails the connect
+	// unless the leader of the expected cluster is found:
+	var identityErr error
 
-	validConfigs := map[string]*configs.YBSingleNodeClientConfig{}
-	for _, hostPort := range c.config.MasterHostPort {
-		validConfigs[hostPort] = &configs.YBSingleNodeClientConfig{
-			MasterHostPort: hostPort,
-			TLSConfig:      tlsConfig,
-			OpTimeout:      uint32(c.config.OpTimeout.Milliseconds()),
+	tried := map[string]struct{}{}
+	for _, hostPort := range preferred {
+		if _, ok := tried[hostPort]; ok || hostPort == "" {
+			continue
+		}
+		tried[hostPort] = struct{}{}
+		singleNodeClient, err := c.connectLeader(ctx, hostPort, tlsConfig)
+		if err == nil {
+			return hostPort, singleNodeClient, nil
 		}
+		c.metricsCallback.ClientError()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", nil, ctxErr
+		}
+		if isClusterIdentityError(err) {
+			identityErr = err
+		}
+		c.logger.Debug("preferred master is not the leader, querying all masters",
+			"host-port", hostPort,
+			"reason", err)
+	}
+
+	type leaderResult struct {
+		hostPort string
+		client   YBConnectedClient
 	}
 
-	chanConnectedClient := make(chan YBConnectedClient, 1)
-	chanErrors := make(chan error)
+	// the context is cancelled when the leader is found
+	// so late leaders close their clients:
+	fanOutCtx, fanOutCancel := context.WithCancel(ctx)
+	defer fanOutCancel()
+
+	// preferred addresses have already been asked:
+	remaining := []string{}
+	for _, hostPort := range addresses {
+		if _, ok := tried[hostPort]; !ok {
+			remaining = append(remaining, hostPort)
+		}
+	}
+	if len(remaining) == 0 {
+		if identityErr != nil {
+			return "", nil, identityErr
+		}
+		return "", nil, &clientErrors.NoLeaderError{}
+	}
+
+	chanLeader := make(chan *leaderResult, 1)
+	chanErrors := make(chan error, len(remaining))
 	var done uint64
-	max := uint64(len(validConfigs))
+	max := uint64(len(remaining))
 
-	for hostPort, cliConfig := range validConfigs {
-		go func(thisHostPort string, thisConfig *configs.YBSingleNodeClientConfig) {
-			singleNodeClient, err := NewDefaultConnector().
-				WithLogger(c.logger.Named("connected-client")).
-				WithMetricsCallback(c.metricsCallback).Connect(thisConfig)
+	for _, hostPort := range remaining {
+		go func(thisHostPort string) {
+			singleNodeClient, err := c.connectLeader(fanOutCtx, thisHostPort, tlsConfig)
 			if err != nil {
-				c.logger.Error("failed creating a client",
-					"reason", err,
-					"host-port", thisHostPort)
 				chanErrors <- err
 				return
 			}
-
 			select {
-			case err := <-singleNodeClient.OnConnectError():
-
-				c.logger.Error("connection error",
-					"reason", err,
-					"host-port", thisHostPort)
+			case chanLeader <- &leaderResult{hostPort: thisHostPort, client: singleNodeClient}:
+			case <-fanOutCtx.Done():
 				singleNodeClient.Close()
-				chanErrors <- err
+			}
+		}(hostPort)
+	}
 
-			case <-singleNodeClient.OnConnected():
+	for {
+		select {
+		case err := <-chanErrors:
+			c.metricsCallback.ClientError()
+			if isClusterIdentityError(err) {
+				identityErr = err
+			}
+			atomic.AddUint64(&done, 1)
+			if atomic.LoadUint64(&done) == max {
+				if identityErr != nil {
+					return "", nil, identityErr
+				}
+				return "", nil, &clientErrors.NoLeaderError{}
+			}
+		case leader := <-chanLeader:
+			return leader.hostPort, leader.client, nil
+		case <-time.After(c.config.OpTimeout):
+			c.metricsCallback.ClientError()
+			return "", nil, errLeaderWaitTimeout
+		case <-ctx.Done():
+			c.metricsCallback.ClientError()
+			return "", nil, ctx.Err()
+		}
+	}
 
-				masterRegistration, err := singleNodeClient.GetMasterRegistration()
+}
 
-				if err != nil {
-					c.logger.Error("failed querying master registration",
-						"reason", err,
-						"host-port", thisHostPort)
-					singleNodeClient.Close()
-					chanErrors <- err
-					return
-				}
+// connectMaster connects to the master regardless of its role.
+func (c *defaultYBClient) connectMaster(ctx context.Context, hostPort string, tlsConfig *tls.Config) (YBConnectedClient, error) {
+	return connectSingleNode(ctx, NewDefaultConnector().
+		WithDialer(c.dialer).
+		WithLogger(c.logger.Named("connected-client")).
+		WithMetricsCallback(c.metricsCallback).
+		WithServiceRegistry(c.svcRegistry).
+		WithTracerProvider(c.tracerProvider).
+		WithVersionProfiles(c.versionProfiles...), &configs.YBSingleNodeClientConfig{
+		MasterHostPort:      hostPort,
+		TLSConfig:           tlsConfig,
+		OpTimeout:           uint32(c.config.OpTimeout.Milliseconds()),
+		MaxFrameSize:        c.config.MaxFrameSize,
+		MaxInFlightRequests: c.config.MaxInFlightRequests,
+	})
+}
 
-				if masterRegistration == nil {
-					c.logger.Trace("master did not send with registration info",
-						"host-port", thisHostPort)
-					singleNodeClient.Close()
-					chanErrors <- fmt.Errorf("master %s did not send registration info", thisHostPort)
-					return
-				}
+// connectLeader connects to the master and returns the connected client
+// only if the master is the leader.
+func (c *defaultYBClient) connectLeader(ctx context.Context, hostPort string, tlsConfig *tls.Config) (YBConnectedClient, error) {
 
-				if masterRegistration.Role == nil {
-					c.logger.Trace("master did not report its raft peer role",
-						"host-port", thisHostPort)
-					singleNodeClient.Close()
-					chanErrors <- fmt.Errorf("master %s did not report its raft peer role", thisHostPort)
-					return
-				}
+	singleNodeClient, err := c.connectMaster(ctx, hostPort, tlsConfig)
+	if err != nil {
+		c.logger.Error("connection error",
+			"reason", err,
+			"host-port", hostPort)
+		return nil, err
+	}
 
-				if *masterRegistration.Role != ybApi.PeerRole_LEADER {
-					c.logger.Trace("master not leader",
-						"host-port", thisHostPort)
-					singleNodeClient.Close()
-					chanErrors <- fmt.Errorf("master %s not leader", thisHostPort)
-					return
-				}
+	masterRegistration, err := singleNodeClient.GetMasterRegistration()
 
-				c.logger.Info("found master leader", "host-port", thisHostPort)
-				chanConnectedClient <- singleNodeClient
-			}
+	if err != nil {
+		c.logger.Error("failed querying master registration",
+			"reason", err,
+			"host-port", hostPort)
+		singleNodeClient.Close()
+		return nil, err
+	}
 
-		}(hostPort, cliConfig)
+	if masterRegistration == nil {
+		c.logger.Trace("master did not send with registration info",
+			"host-port", hostPort)
+		singleNodeClient.Close()
+		return nil, fmt.Errorf("master %s did not send registration info", hostPort)
 	}
 
-	for {
-		select {
-		case <-chanErrors:
-			c.metricsCallback.ClientError()
-			atomic.AddUint64(&done, 1)
-			if atomic.LoadUint64(&done) == max {
-				c.isConnecting = false
-				return &clientErrors.NoLeaderError{}
+	if masterRegistration.Role == nil {
+		c.logger.Trace("master did not report its raft peer role",
+			"host-port", hostPort)
+		singleNodeClient.Close()
+		return nil, fmt.Errorf("master %s did not report its raft peer role", hostPort)
+	}
+
+	if *masterRegistration.Role != ybApi.PeerRole_LEADER {
+		c.logger.Trace("master not leader",
+			"host-port", hostPort)
+		singleNodeClient.Close()
+		return nil, fmt.Errorf("master %s not leader", hostPort)
+	}
+
+	if err := c.verifyClusterIdentity(ctx, hostPort, masterRegistration, singleNodeClient); err != nil {
+		c.logger.Error("master leader rejected",
+			"reason", err,
+			"host-port", hostPort)
+		singleNodeClient.Close()
+		return nil, err
+	}
+
+	c.logger.Info("found master leader", "host-port", hostPort)
+	return singleNodeClient, nil
+}
+
+// verifyClusterIdentity verifies the master leader against the expected master UUIDs
+// and the expected cluster UUID, if configured.
+func (c *defaultYBClient) verifyClusterIdentity(ctx context.Context, hostPort string, masterRegistration *ybApi.GetMasterRegistrationResponsePB, singleNodeClient YBConnectedClient) error {
+	if err := c.verifyMasterUUID(hostPort, masterRegistration); err != nil {
+		return err
+	}
+	if c.config.ExpectedClusterUUID != "" {
+		response := &ybApi.GetMasterClusterConfigResponsePB{}
+		if err := singleNodeClient.ExecuteContext(ctx, &ybApi.GetMasterClusterConfigRequestPB{}, response); err != nil {
+			return err
+		}
+		if err := clientErrors.NewMasterError(response.Error); err != nil {
+			return err
+		}
+		if reported := response.GetClusterConfig().GetClusterUuid(); reported != c.config.ExpectedClusterUUID {
+			return &clientErrors.ClusterUUIDMismatchError{
+				Address:  hostPort,
+				Expected: c.config.ExpectedClusterUUID,
+				Reported: reported,
 			}
-		case connectedClient := <-chanConnectedClient:
-			c.logger.Debug("Setting connected client...")
-			c.metricsCallback.ClientConnect()
-			c.connectedClient = connectedClient
-			c.isConnecting = false
-			c.isConnected = true
+		}
+	}
+	return nil
+}
+
+// verifyMasterUUID verifies the master against the expected master UUIDs, if configured.
+func (c *defaultYBClient) verifyMasterUUID(hostPort string, masterRegistration *ybApi.GetMasterRegistrationResponsePB) error {
+	if len(c.config.ExpectedMasterUUIDs) == 0 {
+		return nil
+	}
+	reported := string(masterRegistration.GetInstanceId().GetPermanentUuid())
+	for _, expected := range c.config.ExpectedMasterUUIDs {
+		if expected == reported {
 			return nil
-		case <-time.After(c.config.OpTimeout):
-			c.metricsCallback.ClientError()
-			c.isConnecting = false
-			return errLeaderWaitTimeout
 		}
 	}
+	return &clientErrors.MasterUUIDMismatchError{
+		Address:  hostPort,
+		Expected: c.config.ExpectedMasterUUIDs,
+		Reported: reported,
+	}
+}
 
+// isClusterIdentityError returns true if the error is a cluster or master UUID mismatch.
+func isClusterIdentityError(err error) bool {
+	var clusterErr *clientErrors.ClusterUUIDMismatchError
+	var masterErr *clientErrors.MasterUUIDMismatchError
+	return errors.As(err, &clusterErr) || errors.As(err, &masterErr)
+}
+
+func (c *defaultYBClient) currentClient() (YBConnectedClient, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.state == StateClosed {
+		return nil, clientErrors.ErrClosed
+	}
+	// calls in progress continue while the client is shutting down:
+	if c.state != StateReady && c.state != StateReconnecting && c.state != StateShuttingDown {
+		return nil, errNotConnected
+	}
+	if c.connectedClient == nil {
+		return nil, errNoClient
+	}
+	return c.connectedClient, nil
 }
 
-func (c *defaultYBClient) reconnect() error {
+// reconnectOp is a reconnect in progress,
+// concurrent reconnects wait for its result.
+type reconnectOp struct {
+	chanDone chan struct{}
+	err      error
+}
+
+// reconnect replaces the failed connected client with a new one.
+// Concurrent calls failing on the same connected client reconnect only once,
+// the first caller reconnects and the others wait for its result.
+// The leader hint from the failed master and the last known leader are tried
+// before querying all masters.
+func (c *defaultYBClient) reconnect(ctx context.Context, failed YBConnectedClient) error {
+	c.lock.Lock()
+	if c.isClosedUnsafe() {
+		c.lock.Unlock()
+		return clientErrors.ErrClosed
+	}
+	if c.state == StateReady && c.connectedClient != nil && c.connectedClient != failed {
+		c.lock.Unlock()
+		return nil
+	}
+	if inProgress := c.reconnecting; inProgress != nil {
+		c.lock.Unlock()
+		select {
+		case <-inProgress.chanDone:
+			return inProgress.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	op := &reconnectOp{chanDone: make(chan struct{})}
+	c.reconnecting = op
+	lastLeader := c.leaderAddress
+	addresses := c.masterAddressesUnsafe()
+	c.setStateUnsafe(StateReconnecting)
+	c.lock.Unlock()
+
+	// the reconnect is cancelled when the client is shutting down:
+	ctx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+	go func() {
+		select {
+		case <-c.chanShutdown:
+			cancelFunc()
+		case <-ctx.Done():
+		}
+	}()
+
+	preferred := []string{}
+	if hint := c.leaderHint(ctx, failed); hint != "" {
+		preferred = append(preferred, hint)
+	}
+	preferred = append(preferred, lastLeader)
+
 	// ignore close error
 	// if the client isn't connected, it does not matter to us
-	c.closeUnsafe()
-	return c.connectUnsafe()
+	failed.Close()
+
+	leaderAddress, connectedClient, err := c.findLeader(ctx, preferred, addresses)
+
+	c.lock.Lock()
+	if c.isClosedUnsafe() {
+		// closed while reconnecting, never bring a closed client back:
+		if err == nil {
+			connectedClient.Close()
+		}
+		err = clientErrors.ErrClosed
+	} else if err == nil {
+		c.setLeaderUnsafe(leaderAddress, connectedClient)
+	}
+	c.reconnecting = nil
+	leaderChangeHooks := c.hooks.onLeaderChange
+	reconnectHooks := c.hooks.onReconnect
+	c.lock.Unlock()
+
+	op.err = err
+	close(op.chanDone)
+
+	if err == nil {
+		runLeaderChangeHooks(leaderChangeHooks, lastLeader, leaderAddress)
+	}
+	for _, hook := range reconnectHooks {
+		hook(leaderAddress, err)
+	}
+	return err
+}
+
+// leaderHint asks the failed master for the current leader address.
+// A master responding with NOT_THE_LEADER is still able to list all masters
+// with their roles. Returns an empty string if the leader is not known.
+func (c *defaultYBClient) leaderHint(ctx context.Context, failed YBConnectedClient) string {
+	timeout := leaderHintTimeout
+	if c.config.OpTimeout < timeout {
+		timeout = c.config.OpTimeout
+	}
+	hintCtx, cancelFunc := context.WithTimeout(ctx, timeout)
+	defer cancelFunc()
+	response := &ybApi.ListMastersResponsePB{}
+	if err := failed.ExecuteContext(hintCtx, &ybApi.ListMastersRequestPB{}, response); err != nil {
+		return ""
+	}
+	for _, entry := range response.Masters {
+		if entry.GetRole() != ybApi.PeerRole_LEADER {
+			continue
+		}
+		for _, hostPort := range entry.GetRegistration().GetPrivateRpcAddresses() {
+			return c.config.TranslateAddress(hostPortPBToString(hostPort))
+		}
+	}
+	return ""
 }
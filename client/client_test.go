@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// testListen starts a listener, every accepted connection is handed over to the handler.
+func testListen(t *testing.T, handler func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed creating a listener: '%v'", err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() {
+				conn.Close()
+			})
+			go handler(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// testServer starts a listener serving the YugabyteDB wire protocol.
+// All requests are handed over to the handler.
+func testServer(t *testing.T, handler func(request *testRequest)) string {
+	return testListen(t, func(conn net.Conn) {
+		testServe(t, conn, handler)
+	})
+}
+
+// testMaster starts a listener serving the YugabyteDB wire protocol.
+// GetMasterRegistration is answered with the leader role,
+// all other requests are handed over to the handler.
+func testMaster(t *testing.T, handler func(request *testRequest)) string {
+	return testMasterWithRole(t, func() ybApi.PeerRole {
+		return ybApi.PeerRole_LEADER
+	}, handler)
+}
+
+// testMasterWithRole starts a listener serving the YugabyteDB wire protocol.
+// GetMasterRegistration is answered with the role returned by the role function,
+// all other requests are handed over to the handler.
+func testMasterWithRole(t *testing.T, role func() ybApi.PeerRole, handler func(request *testRequest)) string {
+	var address string
+	address = testServer(t, func(request *testRequest) {
+		if !testRespondMasterRegistration(request, address, role) {
+			handler(request)
+		}
+	})
+	return address
+}
+
+// testRespondMasterRegistration responds to GetMasterRegistration with the role
+// returned by the role function. Returns false if the request is not GetMasterRegistration.
+func testRespondMasterRegistration(request *testRequest, uuid string, role func() ybApi.PeerRole) bool {
+	if request.header.GetRemoteMethod().GetMethodName() != "GetMasterRegistration" {
+		return false
+	}
+	request.respond(&ybApi.GetMasterRegistrationResponsePB{
+		InstanceId: &ybApi.NodeInstancePB{
+			PermanentUuid: []byte(uuid),
+			InstanceSeqno: utils.PInt64(0),
+		},
+		Role: role().Enum(),
+	})
+	return true
+}
+
+// testNotTheLeader returns a master error response for a follower master.
+func testNotTheLeader() *ybApi.MasterErrorPB {
+	return &ybApi.MasterErrorPB{
+		Code: ybApi.MasterErrorPB_NOT_THE_LEADER.Enum(),
+		Status: &ybApi.AppStatusPB{
+			Code: ybApi.AppStatusPB_ILLEGAL_STATE.Enum(),
+		},
+	}
+}
+
+// testClient returns a connected client. The configure functions
+// are applied before connecting.
+func testClient(t *testing.T, config *configs.YBClientConfig, configure ...func(YBClient) YBClient) YBClient {
+	c := NewYBClient(config).WithLogger(hclog.NewNullLogger())
+	for _, f := range configure {
+		c = f(c)
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("expected client to connect but received: '%v'", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func TestClientExecuteContext(t *testing.T) {
+
+	t.Run("it=executes against the leader", func(tt *testing.T) {
+		address := testMaster(tt, func(request *testRequest) {
+			request.respond(&ybApi.ListMastersResponsePB{})
+		})
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second,
+		})
+		assert.Nil(tt, c.ExecuteContext(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+	})
+
+	t.Run("it=reconnects after the master resets the connection", func(tt *testing.T) {
+		var calls int32
+		address := testMaster(tt, func(request *testRequest) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				request.reset()
+				return
+			}
+			request.respond(&ybApi.ListTablesResponsePB{})
+		})
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort:         []string{address},
+			OpTimeout:              time.Second,
+			RetryInterval:          time.Millisecond,
+			ReconnectRetryInterval: time.Millisecond,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		assert.Nil(tt, c.ExecuteContext(ctx, &ybApi.ListTablesRequestPB{}, &ybApi.ListTablesResponsePB{}))
+		assert.Equal(tt, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("it=interrupts retry sleeps when the context is done", func(tt *testing.T) {
+		address := testMaster(tt, func(request *testRequest) {
+			// not a valid protobuf payload:
+			request.respondBytes([]byte{0xff, 0xff, 0xff})
+		})
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second,
+			RetryInterval:  time.Minute,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+		defer cancel()
+		start := time.Now()
+		err := c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.Equal(tt, context.DeadlineExceeded, err)
+		assert.Less(tt, int64(time.Since(start)), int64(time.Second*5))
+	})
+
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ReadFileDescriptorSet reads a serialized FileDescriptorSet, also known as a protoset file,
+// for example produced with protoc --descriptor_set_out --include_imports.
+func ReadFileDescriptorSet(path string) (*descriptorpb.FileDescriptorSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFileDescriptorSet(data)
+}
+
+// ParseFileDescriptorSet parses a serialized FileDescriptorSet.
+// Options of the YugabyteDB services, like yb.rpc.custom_service_name, are resolved.
+func ParseFileDescriptorSet(data []byte) (*descriptorpb.FileDescriptorSet, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := (proto.UnmarshalOptions{Resolver: protoregistry.GlobalTypes}).Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("invalid file descriptor set: %v", err)
+	}
+	return set, nil
+}
+
+// newFilesFromSet builds the file descriptors of the set.
+// Files of the set take precedence over the files of the fallback registries
+// so the set can override the compiled in definitions. Imports missing
+// in the set are resolved from the fallback registries.
+func newFilesFromSet(set *descriptorpb.FileDescriptorSet, fallback ...*protoregistry.Files) (*protoregistry.Files, error) {
+	files := &protoregistry.Files{}
+	resolver := chainedResolver(append([]*protoregistry.Files{files}, fallback...))
+	pending := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, file := range set.GetFile() {
+		if _, ok := pending[file.GetName()]; ok {
+			return nil, fmt.Errorf("duplicate file %s in file descriptor set", file.GetName())
+		}
+		pending[file.GetName()] = file
+	}
+	// files are built in the dependency order:
+	var build func(name string, path []string) error
+	build = func(name string, path []string) error {
+		file, ok := pending[name]
+		if !ok {
+			return nil
+		}
+		for _, seen := range path {
+			if seen == name {
+				return fmt.Errorf("import cycle in file descriptor set: %v", append(path, name))
+			}
+		}
+		for _, dependency := range file.GetDependency() {
+			if err := build(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		delete(pending, name)
+		descriptor, err := protodesc.NewFile(file, resolver)
+		if err != nil {
+			return fmt.Errorf("invalid file %s in file descriptor set: %v", name, err)
+		}
+		return files.RegisterFile(descriptor)
+	}
+	for _, file := range set.GetFile() {
+		if err := build(file.GetName(), nil); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// chainedResolver resolves files and descriptors from the first registry having them.
+type chainedResolver []*protoregistry.Files
+
+func (r chainedResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	for _, files := range r {
+		if descriptor, err := files.FindFileByPath(path); err == nil {
+			return descriptor, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r chainedResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	for _, files := range r {
+		if descriptor, err := files.FindDescriptorByName(name); err == nil {
+			return descriptor, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// testExtraDescriptorSet returns a file descriptor set with a master service
+// unknown to the client. The request message has the additional fields.
+func testExtraDescriptorSet(extraFields ...string) *descriptorpb.FileDescriptorSet {
+	serviceOptions := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(serviceOptions, ybApi.E_CustomServiceName, "yb.master.MasterService")
+	requestFields := []*descriptorpb.FieldDescriptorProto{
+		{
+			Name:   utils.PString("name"),
+			Number: utils.PInt32(1),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		},
+	}
+	for i, field := range extraFields {
+		requestFields = append(requestFields, &descriptorpb.FieldDescriptorProto{
+			Name:   utils.PString(field),
+			Number: utils.PInt32(int32(i + 2)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   descriptorpb.FieldDescriptorProto_TYPE_UINT32.Enum(),
+		})
+	}
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:       utils.PString("yb/master/extra.proto"),
+				Package:    utils.PString("yb.master.extra"),
+				Dependency: []string{ybApi.File_yb_master_master_types_proto.Path()},
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name:  utils.PString("ListExtrasRequestPB"),
+						Field: requestFields,
+					},
+					{
+						Name: utils.PString("ListExtrasResponsePB"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name:     utils.PString("error"),
+								Number:   utils.PInt32(1),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+								TypeName: utils.PString(".yb.master.MasterErrorPB"),
+							},
+							{
+								Name:   utils.PString("names"),
+								Number: utils.PInt32(2),
+								Label:  descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+								Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							},
+						},
+					},
+				},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{
+						Name: utils.PString("MasterExtra"),
+						Method: []*descriptorpb.MethodDescriptorProto{
+							{
+								Name:       utils.PString("ListExtras"),
+								InputType:  utils.PString(".yb.master.extra.ListExtrasRequestPB"),
+								OutputType: utils.PString(".yb.master.extra.ListExtrasResponsePB"),
+							},
+						},
+						Options: serviceOptions,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestLoadFileDescriptorSet(t *testing.T) {
+
+	t.Run("it=registers the services of the set", func(tt *testing.T) {
+		registry := NewLoadedServiceRegistry()
+		assert.Nil(tt, registry.LoadFileDescriptorSet(testExtraDescriptorSet()))
+		for _, service := range []string{"yb.master.MasterService", "yb.master.extra.MasterExtra"} {
+			method, ok := registry.LookupMethod(service, "ListExtras")
+			if !assert.True(tt, ok, "service %s", service) {
+				continue
+			}
+			svcInfo := registry.Get(dynamicpb.NewMessage(method.Input()))
+			if assert.NotNil(tt, svcInfo) {
+				assert.Equal(tt, "yb.master.MasterService", svcInfo.Service())
+				assert.Equal(tt, "ListExtras", svcInfo.Method())
+			}
+		}
+		// compiled in definitions are still available:
+		_, ok := registry.LookupMethod("yb.master.MasterService", "ListMasters")
+		assert.True(tt, ok)
+		_, ok = LookupMethod("yb.master.MasterService", "ListExtras")
+		assert.False(tt, ok)
+	})
+
+	t.Run("it=overrides previously loaded definitions", func(tt *testing.T) {
+		registry := NewLoadedServiceRegistry()
+		assert.Nil(tt, registry.LoadFileDescriptorSet(testExtraDescriptorSet()))
+		assert.Nil(tt, registry.LoadFileDescriptorSet(testExtraDescriptorSet("limit")))
+		method, ok := registry.LookupMethod("yb.master.MasterService", "ListExtras")
+		if assert.True(tt, ok) {
+			assert.NotNil(tt, method.Input().Fields().ByName("limit"))
+		}
+	})
+
+	t.Run("it=resolves the service name of options parsed without extensions", func(tt *testing.T) {
+		serialized, err := proto.Marshal(testExtraDescriptorSet())
+		assert.Nil(tt, err)
+		set := &descriptorpb.FileDescriptorSet{}
+		assert.Nil(tt, (proto.UnmarshalOptions{Resolver: &protoregistry.Types{}}).Unmarshal(serialized, set))
+		registry := NewDefaultServiceRegistry()
+		assert.Nil(tt, registry.LoadFileDescriptorSet(set))
+		_, ok := registry.LookupMethod("yb.master.MasterService", "ListExtras")
+		assert.True(tt, ok)
+	})
+
+	t.Run("it=reads protoset files", func(tt *testing.T) {
+		serialized, err := proto.Marshal(testExtraDescriptorSet())
+		assert.Nil(tt, err)
+		path := filepath.Join(tt.TempDir(), "extra.protoset")
+		assert.Nil(tt, ioutil.WriteFile(path, serialized, 0644))
+		set, err := ReadFileDescriptorSet(path)
+		if assert.Nil(tt, err) {
+			assert.True(tt, proto.Equal(testExtraDescriptorSet(), set))
+		}
+		_, err = ParseFileDescriptorSet([]byte{0xff, 0xff})
+		assert.Error(tt, err)
+	})
+
+	t.Run("it=rejects sets with unresolved imports", func(tt *testing.T) {
+		set := testExtraDescriptorSet()
+		set.File[0].Dependency = append(set.File[0].Dependency, "yb/no/such.proto")
+		registry := NewLoadedServiceRegistry()
+		assert.Error(tt, registry.LoadFileDescriptorSet(set))
+		_, ok := registry.LookupMethod("yb.master.MasterService", "ListExtras")
+		assert.False(tt, ok)
+	})
+
+}
+
+func TestExecuteJSONWithFileDescriptorSet(t *testing.T) {
+
+	registry := NewLoadedServiceRegistry()
+	if err := registry.LoadFileDescriptorSet(testExtraDescriptorSet()); err != nil {
+		t.Fatalf("expected set to load but received: '%v'", err)
+	}
+	method, _ := registry.LookupMethod("yb.master.MasterService", "ListExtras")
+
+	address := testMaster(t, func(request *testRequest) {
+		payload := dynamicpb.NewMessage(method.Input())
+		proto.Unmarshal(request.payload, payload)
+		response := dynamicpb.NewMessage(method.Output())
+		response.Mutable(method.Output().Fields().ByName("names")).List().
+			Append(payload.Get(method.Input().Fields().ByName("name")))
+		request.respond(response)
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{address},
+		OpTimeout:      time.Second,
+	}, func(c YBClient) YBClient {
+		return c.WithServiceRegistry(registry)
+	})
+
+	t.Run("it=executes methods of the loaded set", func(tt *testing.T) {
+		output, err := c.ExecuteJSON(context.Background(), "yb.master.MasterService", "ListExtras", []byte(`{"name": "extra"}`))
+		if assert.Nil(tt, err) {
+			response := dynamicpb.NewMessage(method.Output())
+			assert.Nil(tt, protojson.Unmarshal(output, response))
+			names := response.Get(method.Output().Fields().ByName("names")).List()
+			if assert.Equal(tt, 1, names.Len()) {
+				assert.Equal(tt, "extra", names.Get(0).String())
+			}
+		}
+	})
+
+}
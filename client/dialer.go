@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Dialer establishes the connections used by the connected clients.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// DialerFunc is a function dialing connections.
+// It allows using any connection factory, for example net.Pipe in tests.
+type DialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// DialContext implements Dialer.
+func (f DialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
+// NewTCPDialer returns a dialer with the dial timeout and the TCP keepalive period.
+// Zero timeout means no timeout other than the context deadline,
+// zero keepalive enables keepalive with the operating system default period.
+func NewTCPDialer(timeout, keepAlive time.Duration) Dialer {
+	return &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: keepAlive,
+	}
+}
+
+// NewSOCKS5Dialer returns a dialer connecting through the SOCKS5 proxy at the address.
+// The auth is optional. Connections to the proxy are dialed with the forward dialer.
+func NewSOCKS5Dialer(address string, auth *proxy.Auth, forward Dialer) (Dialer, error) {
+	socksDialer, err := proxy.SOCKS5("tcp", address, auth, &proxyForwardDialer{forward: forward})
+	if err != nil {
+		return nil, err
+	}
+	contextDialer, ok := socksDialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer does not support context")
+	}
+	return DialerFunc(contextDialer.DialContext), nil
+}
+
+// proxyForwardDialer adapts a Dialer to the dialer interfaces of the proxy package.
+type proxyForwardDialer struct {
+	forward Dialer
+}
+
+func (d *proxyForwardDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d *proxyForwardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.forward.DialContext(ctx, network, address)
+}
+
+// NewHTTPConnectDialer returns a dialer connecting through the HTTP proxy
+// with the CONNECT method. The proxy URL scheme must be http or https.
+// User info in the proxy URL is sent as basic proxy authorization.
+// Connections to the proxy are dialed with the forward dialer.
+func NewHTTPConnectDialer(proxyURL *url.URL, forward Dialer) (Dialer, error) {
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported HTTP proxy scheme '%s'", proxyURL.Scheme)
+	}
+	return &httpConnectDialer{proxyURL: proxyURL, forward: forward}, nil
+}
+
+type httpConnectDialer struct {
+	proxyURL *url.URL
+	forward  Dialer
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	proxyAddress := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		port := "80"
+		if d.proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(d.proxyURL.Hostname(), port)
+	}
+	conn, err := d.forward.DialContext(ctx, network, proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+		if err := handshakeContext(ctx, tlsConn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if user := d.proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := request.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the server starts sending data only after the request is sent,
+	// no data is buffered past the response:
+	response, err := http.ReadResponse(bufio.NewReaderSize(conn, 1), request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy CONNECT to %s failed: %s", address, response.Status)
+	}
+	return conn, nil
+}
+
+// handshakeContext runs the TLS handshake, the handshake is interrupted
+// when the context is done.
+func handshakeContext(ctx context.Context, conn *tls.Conn) error {
+	chanErr := make(chan error, 1)
+	go func() {
+		chanErr <- conn.Handshake()
+	}()
+	select {
+	case err := <-chanErr:
+		return err
+	case <-ctx.Done():
+		// closing the connection interrupts the handshake:
+		conn.Close()
+		<-chanErr
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// testProxy starts a proxy listener. The handshake reads the proxy request
+// from the client connection and returns the target address, an empty target
+// closes the connection. After the handshake, the data is copied both ways.
+func testProxy(t *testing.T, handshake func(conn net.Conn, reader *bufio.Reader) string) string {
+	return testListen(t, func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		target := handshake(conn, reader)
+		if target == "" {
+			conn.Close()
+			return
+		}
+		targetConn, err := net.Dial("tcp", target)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		t.Cleanup(func() {
+			targetConn.Close()
+		})
+		go io.Copy(targetConn, reader)
+		io.Copy(conn, targetConn)
+	})
+}
+
+func testHTTPConnectProxy(t *testing.T, expectedAuthorization string) string {
+	return testProxy(t, func(conn net.Conn, reader *bufio.Reader) string {
+		request, err := http.ReadRequest(reader)
+		if err != nil || request.Method != http.MethodConnect {
+			return ""
+		}
+		if request.Header.Get("Proxy-Authorization") != expectedAuthorization {
+			conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+			return ""
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		return request.Host
+	})
+}
+
+func testSOCKS5Proxy(t *testing.T) string {
+	return testProxy(t, func(conn net.Conn, reader *bufio.Reader) string {
+		// greeting: version, number of methods, methods:
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(reader, greeting); err != nil {
+			return ""
+		}
+		if _, err := io.ReadFull(reader, make([]byte, greeting[1])); err != nil {
+			return ""
+		}
+		// no authentication required:
+		conn.Write([]byte{0x05, 0x00})
+		// request: version, command, reserved, address type:
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(reader, request); err != nil {
+			return ""
+		}
+		var host string
+		switch request[3] {
+		case 0x01:
+			address := make([]byte, net.IPv4len)
+			if _, err := io.ReadFull(reader, address); err != nil {
+				return ""
+			}
+			host = net.IP(address).String()
+		case 0x03:
+			length, err := reader.ReadByte()
+			if err != nil {
+				return ""
+			}
+			address := make([]byte, length)
+			if _, err := io.ReadFull(reader, address); err != nil {
+				return ""
+			}
+			host = string(address)
+		default:
+			return ""
+		}
+		port := make([]byte, 2)
+		if _, err := io.ReadFull(reader, port); err != nil {
+			return ""
+		}
+		// succeeded, bound to 0.0.0.0:0:
+		conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	})
+}
+
+func testConnectListMasters(t *testing.T, dialer Dialer, address string) error {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancelFunc()
+	connectedClient, err := connectSingleNode(ctx, NewDefaultConnector().
+		WithDialer(dialer).
+		WithLogger(hclog.NewNullLogger()), &configs.YBSingleNodeClientConfig{
+		MasterHostPort: address,
+		OpTimeout:      1000,
+	})
+	if err != nil {
+		return err
+	}
+	defer connectedClient.Close()
+	return connectedClient.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+}
+
+func TestDialers(t *testing.T) {
+
+	address := testMaster(t, func(request *testRequest) {
+		request.respond(&ybApi.ListMastersResponsePB{})
+	})
+	tcpDialer := NewTCPDialer(time.Second, time.Second)
+
+	t.Run("it=connects through a connection factory", func(tt *testing.T) {
+		dialed := []string{}
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{"master-1:7100"},
+			OpTimeout:      time.Second,
+		}, func(c YBClient) YBClient {
+			return c.WithDialer(DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+				dialed = append(dialed, address)
+				server, client := net.Pipe()
+				go testServe(tt, server, func(request *testRequest) {
+					if request.header.GetRemoteMethod().GetMethodName() == "GetMasterRegistration" {
+						request.respond(&ybApi.GetMasterRegistrationResponsePB{
+							InstanceId: &ybApi.NodeInstancePB{
+								PermanentUuid: []byte(address),
+								InstanceSeqno: utils.PInt64(0),
+							},
+							Role: ybApi.PeerRole_LEADER.Enum(),
+						})
+						return
+					}
+					request.respond(&ybApi.ListMastersResponsePB{})
+				})
+				return client, nil
+			}))
+		})
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Equal(tt, []string{"master-1:7100"}, dialed)
+	})
+
+	t.Run("it=connects through an HTTP CONNECT proxy", func(tt *testing.T) {
+		proxyAddress := testHTTPConnectProxy(tt, "Basic dXNlcjpzZWNyZXQ=")
+		dialer, err := NewHTTPConnectDialer(&url.URL{
+			Scheme: "http",
+			Host:   proxyAddress,
+			User:   url.UserPassword("user", "secret"),
+		}, tcpDialer)
+		assert.Nil(tt, err)
+		assert.Nil(tt, testConnectListMasters(tt, dialer, address))
+	})
+
+	t.Run("it=fails when the HTTP CONNECT proxy refuses the connection", func(tt *testing.T) {
+		proxyAddress := testHTTPConnectProxy(tt, "Basic dXNlcjpzZWNyZXQ=")
+		dialer, err := NewHTTPConnectDialer(&url.URL{Scheme: "http", Host: proxyAddress}, tcpDialer)
+		assert.Nil(tt, err)
+		err = testConnectListMasters(tt, dialer, address)
+		if assert.NotNil(tt, err) {
+			assert.Contains(tt, err.Error(), "407")
+		}
+	})
+
+	t.Run("it=rejects unsupported HTTP proxy schemes", func(tt *testing.T) {
+		_, err := NewHTTPConnectDialer(&url.URL{Scheme: "socks5", Host: "127.0.0.1:1080"}, tcpDialer)
+		assert.NotNil(tt, err)
+	})
+
+	t.Run("it=connects through a SOCKS5 proxy", func(tt *testing.T) {
+		dialer, err := NewSOCKS5Dialer(testSOCKS5Proxy(tt), nil, tcpDialer)
+		assert.Nil(tt, err)
+		assert.Nil(tt, testConnectListMasters(tt, dialer, address))
+	})
+
+	t.Run("it=stops dialing when the context is done", func(tt *testing.T) {
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		cancelFunc()
+		_, err := NewDefaultConnector().
+			WithDialer(DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})).
+			WithLogger(hclog.NewNullLogger()).
+			ConnectContext(ctx, &configs.YBSingleNodeClientConfig{MasterHostPort: address})
+		assert.Equal(tt, context.Canceled, err)
+	})
+
+}
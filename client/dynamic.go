@@ -0,0 +1,112 @@
+package client
+
+import (
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// dynamicCall is a call built from the method name and a JSON payload.
+type dynamicCall struct {
+	svcInfo  ServiceInfo
+	payload  *dynamicpb.Message
+	response *dynamicpb.Message
+}
+
+// newDynamicCall resolves the method in the registry and parses the JSON payload
+// as the input message of the method.
+func newDynamicCall(registry ServiceRegistry, service, method string, jsonPayload []byte) (*dynamicCall, error) {
+	methodDescriptor, ok := registry.LookupMethod(service, method)
+	if !ok {
+		return nil, &clientErrors.UnknownMethodError{
+			Service: service,
+			Method:  method,
+		}
+	}
+	payload := dynamicpb.NewMessage(methodDescriptor.Input())
+	if err := protojson.Unmarshal(jsonPayload, payload); err != nil {
+		return nil, &clientErrors.InvalidJSONPayloadError{
+			Cause:     err,
+			InputType: methodDescriptor.Input().FullName(),
+		}
+	}
+	return &dynamicCall{
+		svcInfo: &defaultServiceInfo{
+			method:  string(methodDescriptor.Name()),
+			service: serviceName(methodDescriptor.Parent().(protoreflect.ServiceDescriptor)),
+		},
+		payload:  payload,
+		response: dynamicpb.NewMessage(methodDescriptor.Output()),
+	}, nil
+}
+
+// checkMethodTypes returns an error if the payload and the response
+// are not the input and the output messages of the method of the service info.
+func checkMethodTypes(registry ServiceRegistry, svcInfo ServiceInfo, payload, response protoreflect.ProtoMessage) error {
+	methodDescriptor, ok := registry.LookupMethod(svcInfo.Service(), svcInfo.Method())
+	if !ok {
+		return &clientErrors.UnknownMethodError{
+			Service: svcInfo.Service(),
+			Method:  svcInfo.Method(),
+		}
+	}
+	for _, check := range []struct {
+		expected protoreflect.FullName
+		message  protoreflect.ProtoMessage
+	}{
+		{expected: methodDescriptor.Input().FullName(), message: payload},
+		{expected: methodDescriptor.Output().FullName(), message: response},
+	} {
+		if received := check.message.ProtoReflect().Descriptor().FullName(); received != check.expected {
+			return &clientErrors.MethodTypeMismatchError{
+				Service:  svcInfo.Service(),
+				Method:   svcInfo.Method(),
+				Expected: check.expected,
+				Received: received,
+			}
+		}
+	}
+	return nil
+}
+
+// options returns the call options with the service info of the call.
+func (c *dynamicCall) options(opts []CallOption) []CallOption {
+	return append(append([]CallOption{}, opts...), callWithServiceInfo(c.svcInfo))
+}
+
+// responseJSON returns the response serialized as JSON.
+func (c *dynamicCall) responseJSON() ([]byte, error) {
+	return protojson.Marshal(c.response)
+}
+
+// masterErrorFromResponse returns the master error of the response.
+// Responses without the generated accessor, for example dynamic messages,
+// are inspected reflectively for an error field of the master error type.
+func masterErrorFromResponse(response protoreflect.ProtoMessage) *ybApi.MasterErrorPB {
+	if response == nil {
+		return nil
+	}
+	if tResponse, ok := response.(clientErrors.AbstractMasterErrorResponse); ok {
+		return tResponse.GetError()
+	}
+	message := response.ProtoReflect()
+	field := message.Descriptor().Fields().ByName("error")
+	if field == nil || field.Message() == nil || !message.Has(field) {
+		return nil
+	}
+	if field.Message().FullName() != (&ybApi.MasterErrorPB{}).ProtoReflect().Descriptor().FullName() {
+		return nil
+	}
+	serialized, err := proto.MarshalOptions{AllowPartial: true}.Marshal(message.Get(field).Message().Interface())
+	if err != nil {
+		return nil
+	}
+	masterError := &ybApi.MasterErrorPB{}
+	if err := (proto.UnmarshalOptions{AllowPartial: true}).Unmarshal(serialized, masterError); err != nil {
+		return nil
+	}
+	return masterError
+}
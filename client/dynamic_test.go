@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestExecuteJSON(t *testing.T) {
+
+	var notTheLeader int32
+	var calls int32
+	address := testMaster(t, func(request *testRequest) {
+		if request.header.GetRemoteMethod().GetMethodName() == "ListMasters" {
+			// leader hint on reconnect:
+			request.respond(&ybApi.ListMastersResponsePB{})
+			return
+		}
+		atomic.AddInt32(&calls, 1)
+		if request.header.GetRemoteMethod().GetServiceName() != "yb.master.MasterService" ||
+			request.header.GetRemoteMethod().GetMethodName() != "ListTables" {
+			t.Errorf("unexpected remote method: %v", request.header.GetRemoteMethod())
+		}
+		payload := &ybApi.ListTablesRequestPB{}
+		utils.DeserializeProto(request.payload, payload)
+		if payload.GetNameFilter() != "" && payload.GetNameFilter() != "table" {
+			t.Errorf("unexpected name filter: %q", payload.GetNameFilter())
+		}
+		if atomic.CompareAndSwapInt32(&notTheLeader, 1, 0) {
+			request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+			return
+		}
+		request.respond(&ybApi.ListTablesResponsePB{
+			Tables: []*ybApi.ListTablesResponsePB_TableInfo{
+				{Id: []byte("id"), Name: utils.PString("table")},
+			},
+		})
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort:         []string{address},
+		OpTimeout:              time.Second,
+		RetryInterval:          time.Millisecond,
+		ReconnectRetryInterval: time.Millisecond,
+	})
+
+	t.Run("it=executes the method with a JSON payload", func(tt *testing.T) {
+		for _, service := range []string{"yb.master.MasterService", "yb.master.MasterDdl"} {
+			output, err := c.ExecuteJSON(context.Background(), service, "ListTables", []byte(`{"nameFilter": "table"}`))
+			if !assert.Nil(tt, err, "service %s", service) {
+				continue
+			}
+			response := &ybApi.ListTablesResponsePB{}
+			assert.Nil(tt, protojson.Unmarshal(output, response))
+			if assert.Equal(tt, 1, len(response.GetTables())) {
+				assert.Equal(tt, "table", response.GetTables()[0].GetName())
+			}
+		}
+	})
+
+	t.Run("it=reconnects on dynamic NOT_THE_LEADER responses", func(tt *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&notTheLeader, 1)
+		output, err := c.ExecuteJSON(context.Background(), "yb.master.MasterService", "ListTables", []byte(`{}`))
+		assert.Nil(tt, err)
+		assert.NotContains(tt, string(output), "NOT_THE_LEADER")
+		assert.Equal(tt, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("it=rejects unknown methods", func(tt *testing.T) {
+		_, err := c.ExecuteJSON(context.Background(), "yb.master.MasterService", "NoSuchMethod", []byte(`{}`))
+		assert.IsType(tt, &clientErrors.UnknownMethodError{}, err)
+	})
+
+	t.Run("it=rejects invalid payloads", func(tt *testing.T) {
+		_, err := c.ExecuteJSON(context.Background(), "yb.master.MasterService", "ListTables", []byte(`{"noSuchField": 1}`))
+		assert.IsType(tt, &clientErrors.InvalidJSONPayloadError{}, err)
+	})
+
+}
+
+func TestMasterErrorFromResponse(t *testing.T) {
+
+	t.Run("it=reads the master error of dynamic messages", func(tt *testing.T) {
+		serialized, err := proto.Marshal(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+		assert.Nil(tt, err)
+		response := dynamicpb.NewMessage((&ybApi.ListTablesResponsePB{}).ProtoReflect().Descriptor())
+		assert.Nil(tt, proto.Unmarshal(serialized, response))
+		masterError := masterErrorFromResponse(response)
+		if assert.NotNil(tt, masterError) {
+			assert.Equal(tt, ybApi.MasterErrorPB_NOT_THE_LEADER, masterError.GetCode())
+		}
+	})
+
+	t.Run("it=ignores responses without a master error", func(tt *testing.T) {
+		assert.Nil(tt, masterErrorFromResponse(dynamicpb.NewMessage((&ybApi.ListTablesResponsePB{}).ProtoReflect().Descriptor())))
+		assert.Nil(tt, masterErrorFromResponse(dynamicpb.NewMessage((&ybApi.ReadResponsePB{}).ProtoReflect().Descriptor())))
+	})
+
+}
+
+func TestExecuteMethod(t *testing.T) {
+
+	chanMethods := make(chan *ybApi.RemoteMethodPB, 1)
+	address := testMaster(t, func(request *testRequest) {
+		chanMethods <- request.header.GetRemoteMethod()
+		request.respond(&ybApi.ListMastersResponsePB{})
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{address},
+		OpTimeout:      time.Second,
+	})
+
+	t.Run("it=executes the method of the service info", func(tt *testing.T) {
+		// the input type is shared with AlterSchema, registered last:
+		svcInfo := NewServiceInfo("yb.tserver.TabletServerAdminService", "BackfillDone")
+		assert.Nil(tt, c.ExecuteMethod(context.Background(), svcInfo,
+			&ybApi.ChangeMetadataRequestPB{TabletId: []byte("tablet-1")}, &ybApi.ChangeMetadataResponsePB{}))
+		remoteMethod := <-chanMethods
+		assert.Equal(tt, "yb.tserver.TabletServerAdminService", remoteMethod.GetServiceName())
+		assert.Equal(tt, "BackfillDone", remoteMethod.GetMethodName())
+	})
+
+	t.Run("it=rejects messages of other methods before sending", func(tt *testing.T) {
+		svcInfo := NewServiceInfo("yb.master.MasterService", "ListMasters")
+		err := c.ExecuteMethod(context.Background(), svcInfo, &ybApi.ListTablesRequestPB{}, &ybApi.ListMastersResponsePB{})
+		if assert.IsType(tt, &clientErrors.MethodTypeMismatchError{}, err) {
+			assert.Equal(tt, protoreflect.FullName("yb.master.ListMastersRequestPB"), err.(*clientErrors.MethodTypeMismatchError).Expected)
+			assert.Equal(tt, protoreflect.FullName("yb.master.ListTablesRequestPB"), err.(*clientErrors.MethodTypeMismatchError).Received)
+		}
+		err = c.ExecuteMethod(context.Background(), svcInfo, &ybApi.ListMastersRequestPB{}, &ybApi.ListTablesResponsePB{})
+		if assert.IsType(tt, &clientErrors.MethodTypeMismatchError{}, err) {
+			assert.Equal(tt, protoreflect.FullName("yb.master.ListMastersResponsePB"), err.(*clientErrors.MethodTypeMismatchError).Expected)
+		}
+		select {
+		case remoteMethod := <-chanMethods:
+			tt.Fatalf("expected nothing to be sent but received a call to '%s'", remoteMethod.GetMethodName())
+		default:
+		}
+	})
+
+	t.Run("it=rejects unknown methods", func(tt *testing.T) {
+		svcInfo := NewServiceInfo("yb.master.OtherService", "ListOtherMasters")
+		err := c.ExecuteMethod(context.Background(), svcInfo, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.IsType(tt, &clientErrors.UnknownMethodError{}, err)
+	})
+
+	t.Run("it=executes the registered method without the service info", func(tt *testing.T) {
+		assert.Nil(tt, c.ExecuteMethod(context.Background(), nil, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		remoteMethod := <-chanMethods
+		assert.Equal(tt, "yb.master.MasterService", remoteMethod.GetServiceName())
+		assert.Equal(tt, "ListMasters", remoteMethod.GetMethodName())
+	})
+
+}
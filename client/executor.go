@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+)
+
+// retryingExecutor executes calls with retries and reconnects.
+// It is shared by the clients talking to masters and tablet servers.
+type retryingExecutor struct {
+	logger          hclog.Logger
+	metricsCallback metrics.Callback
+	// currentClient returns the connected client to execute the call with.
+	currentClient func() (YBConnectedClient, error)
+	// reconnect replaces the failed connected client.
+	reconnect func(ctx context.Context, failed YBConnectedClient) error
+	// chanShutdown is closed when the client shuts down,
+	// it interrupts the waits between reconnects. Optional.
+	chanShutdown <-chan struct{}
+}
+
+// execute executes the payload using the current connected client,
+// retries and reconnects as decided by the retry policy.
+func (e *retryingExecutor) execute(ctx context.Context, retryPolicy RetryPolicy, payload, response protoreflect.ProtoMessage, opts ...CallOption) error {
+
+	// the lock is held only to get the current connected client,
+	// the connected client multiplexes concurrent calls:
+	connectedClient, err := e.currentClient()
+	if err != nil {
+		return err
+	}
+
+	// retries and reconnects are recorded as events of the call span:
+	span := trace.SpanFromContext(ctx)
+
+	started := time.Now()
+	currentAttempt := int32(1)
+
+	for {
+
+		executeErr := connectedClient.ExecuteContext(contextWithAttempt(ctx, currentAttempt), payload, response, opts...)
+
+		// the response might have an error in it, check if this is a response returning ybApi.MasterErrorPB
+		// dynamic responses are checked reflectively:
+		if responseError := masterErrorFromResponse(response); responseError != nil {
+			// was there an error in that response?
+			if masterError := clientErrors.NewMasterError(responseError); masterError != nil {
+				if responseError.Code != nil {
+					responseErrorCode := *responseError.Code
+					if int32(responseErrorCode.Number()) == int32(ybApi.MasterErrorPB_NOT_THE_LEADER.Number()) {
+						e.logger.Warn("execute: response with NOT_THE_LEADER master status code, reconnect", "reason", masterError)
+						executeErr = &clientErrors.RequiresReconnectError{
+							Cause: masterError,
+						}
+					}
+				}
+			}
+		}
+
+		if executeErr == nil {
+			return nil
+		}
+
+		// Previous execute call could have resulted
+		// in an error response.
+		// Because we are reusing response object, we have to reset it!
+		proto.Reset(response)
+
+		// the caller is no longer interested in the result:
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		reportErr := executeErr
+		if tReconnectError, ok := executeErr.(*clientErrors.RequiresReconnectError); ok {
+			reportErr = tReconnectError.Cause
+		}
+
+		decision := retryPolicy.Decide(currentAttempt, time.Since(started), executeErr)
+
+		switch decision.Action {
+
+		case RetryActionRetry:
+			e.logger.Debug("execute: retrying after an error",
+				"attempt", currentAttempt,
+				"delay", decision.Delay,
+				"reason", reportErr)
+			span.AddEvent("retry", trace.WithAttributes(
+				AttributeAttempt.Int(int(currentAttempt)),
+				attribute.String("reason", reportErr.Error())))
+			currentAttempt = currentAttempt + 1
+			if err := sleepContext(ctx, decision.Delay); err != nil {
+				return err
+			}
+			continue
+
+		case RetryActionReconnect:
+			e.logger.Debug("execute: attempting reconnect due to an error",
+				"attempt", currentAttempt,
+				"reason", reportErr)
+			if err := e.sleepReconnect(ctx, decision.Delay); err != nil {
+				return err
+			}
+
+			// reconnect:
+			currentReconnectAttempt := int32(1)
+			for {
+
+				e.metricsCallback.ClientReconnectAttempt()
+				span.AddEvent("reconnect", trace.WithAttributes(
+					attribute.Int("yb.reconnect.attempt", int(currentReconnectAttempt)),
+					attribute.String("reason", reportErr.Error())))
+
+				reconnectErr := e.reconnect(ctx, connectedClient)
+
+				if reconnectErr == nil {
+					e.metricsCallback.ClientReconnectSuccess()
+					break
+				}
+
+				e.metricsCallback.ClientReconnectFailure()
+
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return ctxErr
+				}
+
+				// a closed client is never reconnected
+				// and a master of another cluster is never trusted:
+				if errors.Is(reconnectErr, clientErrors.ErrClosed) || isClusterIdentityError(reconnectErr) {
+					return reconnectErr
+				}
+
+				reconnectDecision := retryPolicy.DecideReconnect(currentReconnectAttempt, time.Since(started), reconnectErr)
+				if reconnectDecision.Action == RetryActionGiveUp {
+					e.logger.Error("execute: failed reconnect, giving up",
+						"attempt", currentReconnectAttempt,
+						"reason", reportErr)
+					return fmt.Errorf("%s: %s", errNotReconnected.Error(), reportErr.Error())
+				}
+
+				e.logger.Error("execute: failed reconnect",
+					"attempt", currentReconnectAttempt,
+					"delay", reconnectDecision.Delay,
+					"reason", reconnectErr)
+
+				currentReconnectAttempt = currentReconnectAttempt + 1
+				if err := e.sleepReconnect(ctx, reconnectDecision.Delay); err != nil {
+					return err
+				}
+
+			}
+
+			connectedClient, err = e.currentClient()
+			if err != nil {
+				return err
+			}
+
+			// retry:
+			e.logger.Info("execute: client successfully reconnected")
+			currentAttempt = currentAttempt + 1
+			continue
+
+		}
+
+		// in case of any other error, no recovery:
+		e.logger.Error("execute: not retrying", "attempt", currentAttempt, "reason", reportErr)
+		return reportErr
+
+	}
+
+}
+
+// sleepReconnect waits for the duration before a reconnect attempt.
+// Returns errors.ErrClosed if the client shuts down while waiting.
+func (e *retryingExecutor) sleepReconnect(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.chanShutdown:
+		return clientErrors.ErrClosed
+	}
+}
+
+// sleepContext waits for the duration or until the context is done,
+// whichever happens first. Returns the context error if the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Future is the result of an asynchronous call.
+type Future struct {
+	chanDone chan struct{}
+	response protoreflect.ProtoMessage
+	err      error
+}
+
+// newFuture runs the execute function in the background.
+// The response is populated by the execute function.
+func newFuture(response protoreflect.ProtoMessage, execute func() error) *Future {
+	f := &Future{
+		chanDone: make(chan struct{}),
+		response: response,
+	}
+	go func() {
+		defer close(f.chanDone)
+		f.err = execute()
+	}()
+	return f
+}
+
+// Done returns a channel closed when the call finishes.
+func (f *Future) Done() <-chan struct{} {
+	return f.chanDone
+}
+
+// Wait waits for the call to finish and returns the call error.
+// Returns the context error if the context is done before the call finishes,
+// the call itself is interrupted only by the context given to the execute call.
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.chanDone:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Response returns the response given to the execute call.
+// The response is populated only after the call finished without an error.
+func (f *Future) Response() protoreflect.ProtoMessage {
+	return f.response
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// testClusterMaster starts a master of the cluster returned by the cluster function.
+// ListTables is answered with NOT_THE_LEADER while the not the leader flag is set.
+func testClusterMaster(t *testing.T, cluster func() string, notTheLeader *int32) string {
+	return testMaster(t, func(request *testRequest) {
+		switch request.header.GetRemoteMethod().GetMethodName() {
+		case "GetMasterClusterConfig":
+			request.respond(&ybApi.GetMasterClusterConfigResponsePB{
+				ClusterConfig: &ybApi.SysClusterConfigEntryPB{
+					Version:     utils.PInt32(0),
+					ClusterUuid: utils.PString(cluster()),
+				},
+			})
+		case "ListMasters":
+			request.respond(&ybApi.ListMastersResponsePB{})
+		default:
+			if atomic.LoadInt32(notTheLeader) == 1 {
+				request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+				return
+			}
+			request.respond(&ybApi.ListTablesResponsePB{})
+		}
+	})
+}
+
+func TestClusterIdentity(t *testing.T) {
+
+	var cluster atomic.Value
+	cluster.Store("cluster-a")
+	var notTheLeader int32
+	address := testClusterMaster(t, func() string {
+		return cluster.Load().(string)
+	}, &notTheLeader)
+
+	connect := func(config *configs.YBClientConfig) (YBClient, error) {
+		c := NewYBClient(config).WithLogger(hclog.NewNullLogger())
+		err := c.Connect()
+		t.Cleanup(func() {
+			c.Close()
+		})
+		return c, err
+	}
+
+	t.Run("it=connects to the expected cluster", func(tt *testing.T) {
+		_, err := connect(&configs.YBClientConfig{
+			MasterHostPort:      []string{address},
+			OpTimeout:           time.Second,
+			ExpectedClusterUUID: "cluster-a",
+			ExpectedMasterUUIDs: []string{"other", address},
+		})
+		assert.Nil(tt, err)
+	})
+
+	t.Run("it=rejects masters of other clusters", func(tt *testing.T) {
+		_, err := connect(&configs.YBClientConfig{
+			MasterHostPort:      []string{address},
+			OpTimeout:           time.Second,
+			ExpectedClusterUUID: "cluster-b",
+		})
+		if assert.IsType(tt, &clientErrors.ClusterUUIDMismatchError{}, err) {
+			assert.Equal(tt, "cluster-a", err.(*clientErrors.ClusterUUIDMismatchError).Reported)
+		}
+	})
+
+	t.Run("it=rejects unexpected masters", func(tt *testing.T) {
+		_, err := connect(&configs.YBClientConfig{
+			MasterHostPort:      []string{address},
+			OpTimeout:           time.Second,
+			ExpectedMasterUUIDs: []string{"other"},
+		})
+		if assert.IsType(tt, &clientErrors.MasterUUIDMismatchError{}, err) {
+			assert.Equal(tt, address, err.(*clientErrors.MasterUUIDMismatchError).Reported)
+		}
+	})
+
+	t.Run("it=verifies the cluster on reconnect", func(tt *testing.T) {
+		c, err := connect(&configs.YBClientConfig{
+			MasterHostPort:         []string{address},
+			OpTimeout:              time.Second,
+			RetryInterval:          time.Millisecond,
+			ReconnectRetryInterval: time.Millisecond,
+			ExpectedClusterUUID:    "cluster-a",
+		})
+		if !assert.Nil(tt, err) {
+			return
+		}
+		cluster.Store("cluster-b")
+		atomic.StoreInt32(&notTheLeader, 1)
+		defer func() {
+			cluster.Store("cluster-a")
+			atomic.StoreInt32(&notTheLeader, 0)
+		}()
+		err = c.ExecuteContext(context.Background(), &ybApi.ListTablesRequestPB{}, &ybApi.ListTablesResponsePB{})
+		var mismatchErr *clientErrors.ClusterUUIDMismatchError
+		assert.True(tt, errors.As(err, &mismatchErr), "unexpected error: %v", err)
+	})
+
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnaryInvoker executes the call and populates the response with the response data.
+type UnaryInvoker func(ctx context.Context, payload, response protoreflect.ProtoMessage) error
+
+// UnaryInterceptor intercepts the execution of a call.
+// The service info contains the service and method names resolved
+// from the service registry for the payload.
+// An interceptor may modify the context, payload and response, inspect or replace
+// the error returned by the invoker, or complete the call without calling the invoker.
+//
+// Interceptors installed on the YBClient wrap a logical call, including all retries
+// and reconnects. Interceptors installed on the Connector wrap every call executed
+// by the connected client.
+type UnaryInterceptor func(ctx context.Context, info ServiceInfo, payload, response protoreflect.ProtoMessage, invoker UnaryInvoker) error
+
+// chainUnaryInterceptors returns an invoker calling the interceptors in order
+// before calling the final invoker. The first interceptor is the outermost one.
+func chainUnaryInterceptors(interceptors []UnaryInterceptor, info ServiceInfo, final UnaryInvoker) UnaryInvoker {
+	invoker := final
+	for idx := len(interceptors) - 1; idx >= 0; idx = idx - 1 {
+		interceptor, next := interceptors[idx], invoker
+		invoker = func(ctx context.Context, payload, response protoreflect.ProtoMessage) error {
+			return interceptor(ctx, info, payload, response, next)
+		}
+	}
+	return invoker
+}
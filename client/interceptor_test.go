@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/metrics"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// testRecordingInterceptor returns an interceptor appending the name
+// and the called method to the calls, before and after calling the invoker.
+func testRecordingInterceptor(lock *sync.Mutex, calls *[]string, name string) UnaryInterceptor {
+	return func(ctx context.Context, info ServiceInfo, payload, response protoreflect.ProtoMessage, invoker UnaryInvoker) error {
+		lock.Lock()
+		*calls = append(*calls, fmt.Sprintf("%s:before:%s.%s", name, info.Service(), info.Method()))
+		lock.Unlock()
+		err := invoker(ctx, payload, response)
+		lock.Lock()
+		*calls = append(*calls, fmt.Sprintf("%s:after:%v", name, err))
+		lock.Unlock()
+		return err
+	}
+}
+
+func TestClientInterceptors(t *testing.T) {
+
+	address := testMaster(t, func(request *testRequest) {
+		request.respond(&ybApi.ListMastersResponsePB{})
+	})
+
+	t.Run("it=calls interceptors in order with the service info", func(tt *testing.T) {
+		lock := &sync.Mutex{}
+		calls := []string{}
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second,
+		}).WithInterceptors(
+			testRecordingInterceptor(lock, &calls, "first"),
+			testRecordingInterceptor(lock, &calls, "second"))
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Equal(tt, []string{
+			"first:before:yb.master.MasterService.ListMasters",
+			"second:before:yb.master.MasterService.ListMasters",
+			"second:after:<nil>",
+			"first:after:<nil>",
+		}, calls)
+	})
+
+	t.Run("it=allows completing the call without the invoker", func(tt *testing.T) {
+		expectedErr := fmt.Errorf("injected fault")
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second,
+		}).WithInterceptors(func(ctx context.Context, info ServiceInfo, payload, response protoreflect.ProtoMessage, invoker UnaryInvoker) error {
+			return expectedErr
+		})
+		assert.Equal(tt, expectedErr, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+	})
+
+	t.Run("it=intercepts calls executed by the connected client", func(tt *testing.T) {
+		lock := &sync.Mutex{}
+		calls := []string{}
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancelFunc()
+		connectedClient, err := connectSingleNode(ctx, NewDefaultConnector().
+			WithLogger(hclog.NewNullLogger()).
+			WithInterceptors(testRecordingInterceptor(lock, &calls, "connected")),
+			&configs.YBSingleNodeClientConfig{
+				MasterHostPort: address,
+				OpTimeout:      1000,
+			})
+		if !assert.Nil(tt, err) {
+			return
+		}
+		defer connectedClient.Close()
+		registration, err := connectedClient.GetMasterRegistration()
+		assert.Nil(tt, err)
+		assert.Equal(tt, ybApi.PeerRole_LEADER, registration.GetRole())
+		assert.Equal(tt, []string{
+			"connected:before:yb.master.MasterService.GetMasterRegistration",
+			"connected:after:<nil>",
+		}, calls)
+	})
+
+	t.Run("it=shares the service registry with connected clients", func(tt *testing.T) {
+		registry := NewDefaultServiceRegistry()
+		registry.Register(string((&ybApi.ListMastersRequestPB{}).ProtoReflect().Descriptor().FullName()),
+			"ListMasters", "test.Renamed")
+		lock := &sync.Mutex{}
+		calls := []string{}
+		c := newSingleNodeClient(&configs.YBSingleNodeClientConfig{}, nil, registry).
+			withInterceptors([]UnaryInterceptor{testRecordingInterceptor(lock, &calls, "connected")}).
+			withLogger(hclog.NewNullLogger()).
+			withMetricsCallback(metrics.Noop())
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		cancelFunc()
+		assert.Equal(tt, context.Canceled, c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Equal(tt, []string{
+			"connected:before:test.Renamed.ListMasters",
+			"connected:after:context canceled",
+		}, calls)
+		assert.Equal(tt, utils.PString("test.Renamed"), registry.Get(&ybApi.ListMastersRequestPB{}).ToRemoteMethodPB().ServiceName)
+	})
+
+}
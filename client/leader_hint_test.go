@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderHint(t *testing.T) {
+
+	var followerA int32
+	var registrationsA, registrationsB, registrationsC int32
+	var addressB string
+
+	addressA := testMasterWithRole(t, func() ybApi.PeerRole {
+		atomic.AddInt32(&registrationsA, 1)
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_FOLLOWER
+		}
+		return ybApi.PeerRole_LEADER
+	}, func(request *testRequest) {
+		if request.header.GetRemoteMethod().GetMethodName() == "ListMasters" {
+			leader := testServerEntry(t, addressB)
+			leader.Role = ybApi.PeerRole_LEADER.Enum()
+			request.respond(&ybApi.ListMastersResponsePB{
+				Masters: []*ybApi.ServerEntryPB{leader},
+			})
+			return
+		}
+		request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+	})
+
+	addressB = testMasterWithRole(t, func() ybApi.PeerRole {
+		atomic.AddInt32(&registrationsB, 1)
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_LEADER
+		}
+		return ybApi.PeerRole_FOLLOWER
+	}, func(request *testRequest) {
+		request.respond(&ybApi.ListTablesResponsePB{})
+	})
+
+	addressC := testMasterWithRole(t, func() ybApi.PeerRole {
+		atomic.AddInt32(&registrationsC, 1)
+		return ybApi.PeerRole_FOLLOWER
+	}, func(request *testRequest) {
+		request.respond(&ybApi.ListTablesResponsePB{})
+	})
+
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{addressA, addressB, addressC},
+		OpTimeout:      time.Second,
+	})
+
+	t.Run("it=reconnects once to the hinted leader", func(tt *testing.T) {
+		// wait for the followers to answer the initial scan:
+		deadline := time.Now().Add(time.Second * 5)
+		for (atomic.LoadInt32(&registrationsB) == 0 || atomic.LoadInt32(&registrationsC) == 0) && time.Now().Before(deadline) {
+			<-time.After(time.Millisecond * 10)
+		}
+		initialA := atomic.LoadInt32(&registrationsA)
+		initialB := atomic.LoadInt32(&registrationsB)
+		initialC := atomic.LoadInt32(&registrationsC)
+
+		atomic.StoreInt32(&followerA, 1)
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancelFunc()
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 10; i = i + 1 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response := &ybApi.ListTablesResponsePB{}
+				assert.Nil(tt, c.ExecuteContext(ctx, &ybApi.ListTablesRequestPB{}, response))
+				assert.Nil(tt, response.Error)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(tt, initialA, atomic.LoadInt32(&registrationsA))
+		assert.Equal(tt, initialB+1, atomic.LoadInt32(&registrationsB))
+		assert.Equal(tt, initialC, atomic.LoadInt32(&registrationsC))
+	})
+
+}
+
+func TestLeaderHintUnresponsive(t *testing.T) {
+
+	var followerA int32
+	var registrationsA, registrationsB int32
+
+	addressA := testMasterWithRole(t, func() ybApi.PeerRole {
+		atomic.AddInt32(&registrationsA, 1)
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_FOLLOWER
+		}
+		return ybApi.PeerRole_LEADER
+	}, func(request *testRequest) {
+		if request.header.GetRemoteMethod().GetMethodName() == "ListMasters" {
+			// never answer the leader hint query
+			return
+		}
+		request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+	})
+
+	addressB := testMasterWithRole(t, func() ybApi.PeerRole {
+		atomic.AddInt32(&registrationsB, 1)
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_LEADER
+		}
+		return ybApi.PeerRole_FOLLOWER
+	}, func(request *testRequest) {
+		request.respond(&ybApi.ListTablesResponsePB{})
+	})
+
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{addressA, addressB},
+		OpTimeout:      time.Second * 10,
+	})
+
+	t.Run("it=does not wait the operation timeout for the leader hint", func(tt *testing.T) {
+		// wait for the follower to answer the initial scan:
+		deadline := time.Now().Add(time.Second * 5)
+		for atomic.LoadInt32(&registrationsB) == 0 && time.Now().Before(deadline) {
+			<-time.After(time.Millisecond * 10)
+		}
+		initialA := atomic.LoadInt32(&registrationsA)
+
+		atomic.StoreInt32(&followerA, 1)
+
+		start := time.Now()
+		response := &ybApi.ListTablesResponsePB{}
+		assert.Nil(tt, c.ExecuteContext(context.Background(), &ybApi.ListTablesRequestPB{}, response))
+		assert.Nil(tt, response.Error)
+		assert.Less(tt, int64(time.Since(start)), int64(leaderHintTimeout*3))
+		// the previous leader is asked once, the fan out skips it:
+		assert.Equal(tt, initialA+1, atomic.LoadInt32(&registrationsA))
+	})
+
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+)
+
+// MasterAddresses returns the master addresses used when connecting.
+func (c *defaultYBClient) MasterAddresses() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.masterAddressesUnsafe()
+}
+
+// masterAddressesUnsafe returns the seed master addresses
+// merged with the discovered master addresses.
+func (c *defaultYBClient) masterAddressesUnsafe() []string {
+	result := []string{}
+	seen := map[string]struct{}{}
+	for _, addresses := range [][]string{c.config.MasterHostPort, c.discoveredMasters} {
+		for _, address := range addresses {
+			if _, ok := seen[address]; ok {
+				continue
+			}
+			seen[address] = struct{}{}
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+// refreshMasters refreshes the discovered master addresses
+// from the list of masters known to the connected master.
+func (c *defaultYBClient) refreshMasters(ctx context.Context) error {
+	request := &ybApi.ListMastersRequestPB{}
+	response := &ybApi.ListMastersResponsePB{}
+	if err := c.ExecuteContext(ctx, request, response); err != nil {
+		return err
+	}
+	if err := clientErrors.NewMasterError(response.Error); err != nil {
+		return err
+	}
+	discovered := []string{}
+	for _, entry := range response.Masters {
+		registration := entry.GetRegistration()
+		for _, hostPort := range append(registration.GetPrivateRpcAddresses(), registration.GetBroadcastAddresses()...) {
+			discovered = append(discovered, c.config.TranslateAddress(hostPortPBToString(hostPort)))
+		}
+	}
+	if len(discovered) == 0 {
+		// never forget known masters because of an empty response:
+		return nil
+	}
+	c.lock.Lock()
+	c.discoveredMasters = discovered
+	c.lock.Unlock()
+	c.logger.Debug("refreshed master addresses", "discovered", discovered)
+	return nil
+}
+
+// startDiscoveryUnsafe starts the background master addresses refresh,
+// if master discovery is enabled.
+func (c *defaultYBClient) startDiscoveryUnsafe() {
+	if c.config.MasterDiscovery != configs.MasterDiscoveryAuto || c.chanStopDiscovery != nil {
+		return
+	}
+	chanStop := make(chan struct{})
+	c.chanStopDiscovery = chanStop
+	go func() {
+		for {
+			ctx, cancelFunc := context.WithTimeout(context.Background(), c.config.OpTimeout)
+			if err := c.refreshMasters(ctx); err != nil {
+				c.logger.Warn("failed refreshing master addresses", "reason", err)
+			}
+			cancelFunc()
+			select {
+			case <-chanStop:
+				return
+			case <-time.After(c.config.MasterRefreshInterval):
+			}
+		}
+	}()
+}
+
+// stopDiscoveryUnsafe stops the background master addresses refresh.
+func (c *defaultYBClient) stopDiscoveryUnsafe() {
+	if c.chanStopDiscovery != nil {
+		close(c.chanStopDiscovery)
+		c.chanStopDiscovery = nil
+	}
+}
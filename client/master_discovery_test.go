@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServerEntry(t *testing.T, address string) *ybApi.ServerEntryPB {
+	entry := testTServerEntry(t, address, address, true)
+	return &ybApi.ServerEntryPB{
+		InstanceId:   entry.InstanceId,
+		Registration: entry.Registration.Common,
+	}
+}
+
+func TestMasterDiscovery(t *testing.T) {
+
+	var followerA int32
+	var callsB int32
+	var addressA, addressB string
+
+	listMasters := func(request *testRequest) bool {
+		if request.header.GetRemoteMethod().GetMethodName() == "ListMasters" {
+			request.respond(&ybApi.ListMastersResponsePB{
+				Masters: []*ybApi.ServerEntryPB{
+					testServerEntry(t, addressA),
+					testServerEntry(t, addressB),
+				},
+			})
+			return true
+		}
+		return false
+	}
+
+	addressB = testMasterWithRole(t, func() ybApi.PeerRole {
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_LEADER
+		}
+		return ybApi.PeerRole_FOLLOWER
+	}, func(request *testRequest) {
+		if listMasters(request) {
+			return
+		}
+		atomic.AddInt32(&callsB, 1)
+		request.respond(&ybApi.ListTablesResponsePB{})
+	})
+
+	addressA = testMasterWithRole(t, func() ybApi.PeerRole {
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_FOLLOWER
+		}
+		return ybApi.PeerRole_LEADER
+	}, func(request *testRequest) {
+		if listMasters(request) {
+			return
+		}
+		if atomic.LoadInt32(&followerA) == 1 {
+			request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+			return
+		}
+		request.respond(&ybApi.ListTablesResponsePB{})
+	})
+
+	t.Run("it=uses only seed masters in seed only mode", func(tt *testing.T) {
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort:        []string{addressA},
+			MasterRefreshInterval: time.Millisecond * 10,
+			OpTimeout:             time.Second,
+		})
+		<-time.After(time.Millisecond * 50)
+		assert.Equal(tt, []string{addressA}, c.MasterAddresses())
+	})
+
+	t.Run("it=reconnects to a discovered master", func(tt *testing.T) {
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort:        []string{addressA},
+			MasterDiscovery:       configs.MasterDiscoveryAuto,
+			MasterRefreshInterval: time.Millisecond * 10,
+			OpTimeout:             time.Second,
+		})
+		deadline := time.Now().Add(time.Second * 5)
+		for len(c.MasterAddresses()) != 2 && time.Now().Before(deadline) {
+			<-time.After(time.Millisecond * 10)
+		}
+		assert.Equal(tt, []string{addressA, addressB}, c.MasterAddresses())
+
+		// the seed master is replaced as the leader by the discovered master:
+		atomic.StoreInt32(&followerA, 1)
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancelFunc()
+		response := &ybApi.ListTablesResponsePB{}
+		assert.Nil(tt, c.ExecuteContext(ctx, &ybApi.ListTablesRequestPB{}, response))
+		assert.Nil(tt, response.Error)
+		assert.Equal(tt, int32(1), atomic.LoadInt32(&callsB))
+	})
+
+}
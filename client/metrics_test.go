@@ -0,0 +1,95 @@
+package client
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/metrics"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRPCMetricsCallback struct {
+	metrics.Callback
+	lock     *sync.Mutex
+	inFlight int
+	events   []*metrics.RPCEvent
+}
+
+func (p *testRPCMetricsCallback) RPCStarted(service, method, host string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.inFlight = p.inFlight + 1
+}
+
+func (p *testRPCMetricsCallback) RPCFinished(event *metrics.RPCEvent) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.inFlight = p.inFlight - 1
+	p.events = append(p.events, event)
+}
+
+func TestClientRPCMetrics(t *testing.T) {
+
+	var calls int32
+	address := testMaster(t, func(request *testRequest) {
+		if request.header.GetRemoteMethod().GetMethodName() == "ListTables" {
+			request.respond(&ybApi.ListTablesResponsePB{
+				Error: &ybApi.MasterErrorPB{
+					Code: ybApi.MasterErrorPB_UNKNOWN_ERROR.Enum(),
+					Status: &ybApi.AppStatusPB{
+						Code: ybApi.AppStatusPB_ILLEGAL_STATE.Enum(),
+					},
+				},
+			})
+			return
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// not a valid protobuf payload:
+			request.respondBytes([]byte{0xff, 0xff, 0xff})
+			return
+		}
+		request.respond(&ybApi.ListMastersResponsePB{})
+	})
+
+	callback := &testRPCMetricsCallback{Callback: metrics.Noop(), lock: &sync.Mutex{}}
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{address},
+		OpTimeout:      time.Second,
+	}, func(c YBClient) YBClient {
+		return c.WithMetricsCallback(callback).
+			WithRetryPolicy(&testCountingRetryPolicy{lock: &sync.Mutex{}, retries: 2})
+	})
+
+	t.Run("it=reports every RPC attempt", func(tt *testing.T) {
+		callback.lock.Lock()
+		callback.events = nil
+		callback.lock.Unlock()
+
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Nil(tt, c.Execute(&ybApi.ListTablesRequestPB{}, &ybApi.ListTablesResponsePB{}))
+
+		callback.lock.Lock()
+		defer callback.lock.Unlock()
+		assert.Equal(tt, 0, callback.inFlight)
+		if !assert.Equal(tt, 3, len(callback.events)) {
+			return
+		}
+		for _, event := range callback.events {
+			assert.Equal(tt, "yb.master.MasterService", event.Service)
+			assert.Equal(tt, address, event.Host)
+		}
+		assert.Equal(tt, "ListMasters", callback.events[0].Method)
+		assert.Equal(tt, metrics.RPCOutcomeError, callback.events[0].Outcome)
+		assert.Equal(tt, ErrorClassRetryable.String(), callback.events[0].ErrorClass)
+		assert.Equal(tt, metrics.RPCOutcomeSuccess, callback.events[1].Outcome)
+		assert.Equal(tt, "", callback.events[1].ErrorClass)
+		assert.Equal(tt, "ListTables", callback.events[2].Method)
+		assert.Equal(tt, metrics.RPCOutcomeMasterError, callback.events[2].Outcome)
+		assert.Equal(tt, "UNKNOWN_ERROR", callback.events[2].ErrorCode)
+	})
+
+}
@@ -0,0 +1,475 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ReadPreference selects the masters executing read-only calls.
+// Calls modifying the cluster state are always executed on the master leader.
+type ReadPreference int
+
+const (
+	// ReadPreferenceLeader executes read-only calls on the master leader.
+	ReadPreferenceLeader ReadPreference = iota
+	// ReadPreferenceFollower executes read-only calls on the master followers,
+	// the leader executes the calls the followers fail or reject.
+	ReadPreferenceFollower
+	// ReadPreferenceNearest executes read-only calls on the master
+	// with the lowest observed latency, leader or follower.
+	ReadPreferenceNearest
+)
+
+func (p ReadPreference) String() string {
+	switch p {
+	case ReadPreferenceLeader:
+		return "leader"
+	case ReadPreferenceFollower:
+		return "follower"
+	case ReadPreferenceNearest:
+		return "nearest"
+	default:
+		return fmt.Sprintf("ReadPreference(%d)", int(p))
+	}
+}
+
+// CallWithReadPreference selects the masters executing a read-only call.
+// The option is ignored for calls modifying the cluster state.
+func CallWithReadPreference(preference ReadPreference) CallOption {
+	return func(o *callOptions) {
+		o.readPreference = preference
+	}
+}
+
+// CallWithHedging executes a read-only call also on the next master
+// if the previous one does not respond within the delay, the first response wins.
+// The option is ignored for calls modifying the cluster state.
+func CallWithHedging(delay time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.hedgingDelay = delay
+	}
+}
+
+// readOnlyMasterMethods are the methods of yb.master.MasterService
+// reading the cluster state any master can answer for.
+var readOnlyMasterMethods = map[string]struct{}{
+	"AreLeadersOnPreferredOnly":      {},
+	"GetBackfillJobs":                {},
+	"GetCDCStream":                   {},
+	"GetColocatedTabletSchema":       {},
+	"GetLeaderBlacklistCompletion":   {},
+	"GetLoadMoveCompletion":          {},
+	"GetMasterClusterConfig":         {},
+	"GetNamespaceInfo":               {},
+	"GetPermissions":                 {},
+	"GetTableLocations":              {},
+	"GetTableSchema":                 {},
+	"GetTabletLocations":             {},
+	"GetTablegroupSchema":            {},
+	"GetTransactionStatusTablets":    {},
+	"GetUDTypeInfo":                  {},
+	"GetUniverseReplication":         {},
+	"GetYsqlCatalogConfig":           {},
+	"IsAlterTableDone":               {},
+	"IsCreateNamespaceDone":          {},
+	"IsCreateTableDone":              {},
+	"IsDeleteNamespaceDone":          {},
+	"IsDeleteTableDone":              {},
+	"IsEncryptionEnabled":            {},
+	"IsFlushTablesDone":              {},
+	"IsInitDbDone":                   {},
+	"IsLoadBalanced":                 {},
+	"IsLoadBalancerIdle":             {},
+	"IsSetupUniverseReplicationDone": {},
+	"IsTruncateTableDone":            {},
+	"ListCDCStreams":                 {},
+	"ListLiveTabletServers":          {},
+	"ListMasters":                    {},
+	"ListNamespaces":                 {},
+	"ListTables":                     {},
+	"ListTablegroups":                {},
+	"ListTabletServers":              {},
+	"ListUDTypes":                    {},
+}
+
+// IsReadOnlyMethod returns true if the method reads the cluster state
+// and can be executed on master followers. All other methods are considered
+// to modify the cluster state.
+func IsReadOnlyMethod(svcInfo ServiceInfo) bool {
+	if svcInfo == nil || svcInfo.Service() != "yb.master.MasterService" {
+		return false
+	}
+	_, ok := readOnlyMasterMethods[svcInfo.Method()]
+	return ok
+}
+
+// readRejectedPenalty is added to the observed latency of a master
+// rejecting a read so nearest reads prefer other masters afterwards.
+const readRejectedPenalty = time.Second
+
+// readCandidate executes a read-only call on a single master.
+type readCandidate struct {
+	address string
+	execute func(ctx context.Context, response protoreflect.ProtoMessage) error
+}
+
+// executeRead executes the read-only call on the masters selected by the read preference.
+// The next master is tried when the previous one fails or, with hedging,
+// does not respond within the hedging delay.
+func (c *defaultYBClient) executeRead(ctx context.Context, options *callOptions,
+	payload, response protoreflect.ProtoMessage,
+	leader func(ctx context.Context, payload, response protoreflect.ProtoMessage) error,
+	opts []CallOption) error {
+
+	c.lock.Lock()
+	leaderAddress := c.leaderAddress
+	addresses := c.masterAddressesUnsafe()
+	c.lock.Unlock()
+
+	// configured and discovered addresses of the same master may differ:
+	followers := []string{}
+	for _, address := range addresses {
+		if c.sameMasterAddress(address, leaderAddress) || c.replicas.isRejected(address) {
+			continue
+		}
+		known := false
+		for _, follower := range followers {
+			if c.sameMasterAddress(address, follower) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			followers = append(followers, address)
+		}
+	}
+
+	leaderCandidate := readCandidate{
+		address: leaderAddress,
+		execute: func(ctx context.Context, response protoreflect.ProtoMessage) error {
+			return leader(ctx, payload, response)
+		},
+	}
+	candidates := []readCandidate{}
+	switch options.readPreference {
+	case ReadPreferenceFollower:
+		for _, address := range c.replicas.rotate(followers) {
+			candidates = append(candidates, c.replicaCandidate(address, payload, opts))
+		}
+		candidates = append(candidates, leaderCandidate)
+	case ReadPreferenceNearest:
+		candidates = append(candidates, leaderCandidate)
+		for _, address := range followers {
+			candidates = append(candidates, c.replicaCandidate(address, payload, opts))
+		}
+		// unknown latencies are zero so every master is measured:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return c.replicas.latency(candidates[i].address) < c.replicas.latency(candidates[j].address)
+		})
+	default:
+		candidates = append(candidates, leaderCandidate)
+		// hedged calls go to the followers after the leader:
+		if options.hedgingDelay > 0 {
+			for _, address := range c.replicas.rotate(followers) {
+				candidates = append(candidates, c.replicaCandidate(address, payload, opts))
+			}
+		}
+	}
+
+	hedgingDelay := options.hedgingDelay
+	if options.sidecars != nil {
+		// concurrent attempts would write to the same sidecars:
+		hedgingDelay = 0
+	}
+	return c.executeCandidates(ctx, candidates, response, hedgingDelay)
+}
+
+// sameMasterAddress returns true if both addresses point at the same master.
+// Either address may be translated already, host names are case insensitive.
+func (c *defaultYBClient) sameMasterAddress(a, b string) bool {
+	for _, left := range []string{a, c.config.TranslateAddress(a)} {
+		for _, right := range []string{b, c.config.TranslateAddress(b)} {
+			if strings.EqualFold(left, right) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// executeCandidates executes the call on the candidates in order until one succeeds.
+// With a hedging delay, the next candidate is started also when the running ones
+// do not respond within the delay. The response of the first successful candidate
+// is copied to the response.
+func (c *defaultYBClient) executeCandidates(ctx context.Context, candidates []readCandidate, response protoreflect.ProtoMessage, hedgingDelay time.Duration) error {
+	ctx, cancelFunc := context.WithCancel(ctx)
+	defer cancelFunc()
+
+	type candidateResult struct {
+		address  string
+		response protoreflect.ProtoMessage
+		err      error
+	}
+	chanResults := make(chan *candidateResult, len(candidates))
+	next := 0
+	running := 0
+	var chanHedge <-chan time.Time
+	start := func() {
+		candidate := candidates[next]
+		next = next + 1
+		running = running + 1
+		go func() {
+			candidateResponse := response.ProtoReflect().New().Interface()
+			started := time.Now()
+			err := candidate.execute(ctx, candidateResponse)
+			if err == nil {
+				c.replicas.observe(candidate.address, time.Since(started))
+			}
+			chanResults <- &candidateResult{address: candidate.address, response: candidateResponse, err: err}
+		}()
+		chanHedge = nil
+		if hedgingDelay > 0 && next < len(candidates) {
+			chanHedge = time.After(hedgingDelay)
+		}
+	}
+
+	start()
+	var lastErr error
+	for {
+		select {
+		case result := <-chanResults:
+			running = running - 1
+			if result.err == nil {
+				proto.Reset(response)
+				proto.Merge(response, result.response)
+				return nil
+			}
+			lastErr = result.err
+			c.logger.Debug("read failed, trying the next master",
+				"host-port", result.address,
+				"reason", result.err)
+			if next < len(candidates) {
+				start()
+			} else if running == 0 {
+				return lastErr
+			}
+		case <-chanHedge:
+			c.logger.Debug("hedging read", "host-port", candidates[next].address)
+			start()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// replicaCandidate executes the read-only call on the master without retries.
+// Calls rejected by the master with NOT_THE_LEADER fail so the next master is tried,
+// the master is not asked again until the leader changes.
+func (c *defaultYBClient) replicaCandidate(address string, payload protoreflect.ProtoMessage, opts []CallOption) readCandidate {
+	return readCandidate{
+		address: address,
+		execute: func(ctx context.Context, response protoreflect.ProtoMessage) error {
+			replica, err := c.replica(ctx, address)
+			if err != nil {
+				return err
+			}
+			if err := replica.ExecuteContext(ctx, payload, response, opts...); err != nil {
+				if ctx.Err() == nil {
+					c.replicas.remove(address, replica)
+				}
+				return err
+			}
+			if masterError := masterErrorFromResponse(response); masterError != nil &&
+				masterError.GetCode() == ybApi.MasterErrorPB_NOT_THE_LEADER {
+				c.replicas.reject(address)
+				return clientErrors.NewMasterError(masterError)
+			}
+			return nil
+		},
+	}
+}
+
+// replica returns the connection to the master, connects if there is no connection yet.
+func (c *defaultYBClient) replica(ctx context.Context, address string) (YBConnectedClient, error) {
+	if replica, ok := c.replicas.get(address); ok {
+		return replica, nil
+	}
+	tlsConfig, err := c.config.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	connectCtx, cancelFunc := context.WithTimeout(ctx, c.config.OpTimeout)
+	defer cancelFunc()
+	replica, err := c.connectMaster(connectCtx, address, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyReplica(connectCtx, address, replica); err != nil {
+		c.logger.Error("master rejected for reads",
+			"reason", err,
+			"host-port", address)
+		replica.Close()
+		return nil, err
+	}
+	return c.replicas.put(address, replica), nil
+}
+
+// verifyReplica verifies the master against the expected master UUIDs and,
+// if the expected cluster UUID is configured, requires the master
+// to be known to the verified master leader.
+func (c *defaultYBClient) verifyReplica(ctx context.Context, address string, replica YBConnectedClient) error {
+	masterRegistration, err := replica.GetMasterRegistration()
+	if err != nil {
+		return err
+	}
+	if err := c.verifyMasterUUID(address, masterRegistration); err != nil {
+		return err
+	}
+	if c.config.ExpectedClusterUUID == "" {
+		return nil
+	}
+	response := &ybApi.ListMastersResponsePB{}
+	if err := c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, response); err != nil {
+		return err
+	}
+	if err := clientErrors.NewMasterError(response.Error); err != nil {
+		return err
+	}
+	reported := string(masterRegistration.GetInstanceId().GetPermanentUuid())
+	expected := []string{}
+	for _, entry := range response.GetMasters() {
+		if string(entry.GetInstanceId().GetPermanentUuid()) == reported {
+			return nil
+		}
+		expected = append(expected, string(entry.GetInstanceId().GetPermanentUuid()))
+	}
+	return &clientErrors.MasterUUIDMismatchError{
+		Address:  address,
+		Expected: expected,
+		Reported: reported,
+	}
+}
+
+// masterReplicas holds the connections to the masters executing read-only calls,
+// the latencies observed for all masters and the masters rejecting reads.
+type masterReplicas struct {
+	connections map[string]YBConnectedClient
+	counter     int
+	latencies   map[string]time.Duration
+	lock        *sync.Mutex
+	rejected    map[string]struct{}
+}
+
+func newMasterReplicas() *masterReplicas {
+	return &masterReplicas{
+		connections: map[string]YBConnectedClient{},
+		latencies:   map[string]time.Duration{},
+		lock:        &sync.Mutex{},
+		rejected:    map[string]struct{}{},
+	}
+}
+
+func (r *masterReplicas) get(address string) (YBConnectedClient, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	replica, ok := r.connections[address]
+	return replica, ok
+}
+
+// put stores the connection, a connection stored in the meantime wins.
+func (r *masterReplicas) put(address string, replica YBConnectedClient) YBConnectedClient {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if existing, ok := r.connections[address]; ok {
+		replica.Close()
+		return existing
+	}
+	r.connections[address] = replica
+	return replica
+}
+
+// remove closes the failed connection unless it has been replaced already.
+func (r *masterReplicas) remove(address string, failed YBConnectedClient) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if replica, ok := r.connections[address]; ok && replica == failed {
+		delete(r.connections, address)
+		delete(r.latencies, address)
+		// ignore close error
+		replica.Close()
+	}
+}
+
+// reject marks the master as not serving reads until the rejected masters
+// are reset and penalizes its observed latency.
+func (r *masterReplicas) reject(address string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.rejected[address] = struct{}{}
+	r.latencies[address] = r.latencies[address] + readRejectedPenalty
+}
+
+func (r *masterReplicas) isRejected(address string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, ok := r.rejected[address]
+	return ok
+}
+
+// resetRejected makes all masters eligible for reads again,
+// called when the leader changes.
+func (r *masterReplicas) resetRejected() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.rejected = map[string]struct{}{}
+}
+
+// rotate returns the addresses rotated by one on every call
+// so the reads are spread over the followers.
+func (r *masterReplicas) rotate(addresses []string) []string {
+	if len(addresses) == 0 {
+		return addresses
+	}
+	r.lock.Lock()
+	offset := r.counter % len(addresses)
+	r.counter = r.counter + 1
+	r.lock.Unlock()
+	return append(append([]string{}, addresses[offset:]...), addresses[:offset]...)
+}
+
+// observe records the latency of a successful call as a moving average.
+func (r *masterReplicas) observe(address string, latency time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if previous, ok := r.latencies[address]; ok {
+		latency = (previous*7 + latency*3) / 10
+	}
+	r.latencies[address] = latency
+}
+
+// latency returns the observed latency of the master, zero if unknown.
+func (r *masterReplicas) latency(address string) time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.latencies[address]
+}
+
+// close closes all connections.
+func (r *masterReplicas) close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for address, replica := range r.connections {
+		// ignore close error
+		replica.Close()
+		delete(r.connections, address)
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReadOnlyMethod(t *testing.T) {
+
+	t.Run("it=classifies read-only master methods", func(tt *testing.T) {
+		for method := range readOnlyMasterMethods {
+			_, ok := LookupMethod("yb.master.MasterService", method)
+			assert.True(tt, ok, "method %s", method)
+			assert.True(tt, IsReadOnlyMethod(NewServiceInfo("yb.master.MasterService", method)))
+		}
+	})
+
+	t.Run("it=classifies other methods as writes", func(tt *testing.T) {
+		assert.False(tt, IsReadOnlyMethod(NewServiceInfo("yb.master.MasterService", "CreateTable")))
+		assert.False(tt, IsReadOnlyMethod(NewServiceInfo("yb.master.MasterService", "GetMasterRegistration")))
+		assert.False(tt, IsReadOnlyMethod(NewServiceInfo("yb.tserver.TabletServerService", "ListTablets")))
+		assert.False(tt, IsReadOnlyMethod(nil))
+	})
+
+}
+
+func TestReadPreference(t *testing.T) {
+
+	var rejectReads int32
+	var delayLeader int32
+	chanServed := make(chan string, 16)
+	serve := func(name string, reject *int32, delay *int32) func(request *testRequest) {
+		return func(request *testRequest) {
+			if request.header.GetRemoteMethod().GetMethodName() == "ListMasters" {
+				request.respond(&ybApi.ListMastersResponsePB{})
+				return
+			}
+			if atomic.LoadInt32(delay) == 1 {
+				<-time.After(time.Second)
+			}
+			chanServed <- name
+			if atomic.LoadInt32(reject) == 1 {
+				request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+				return
+			}
+			request.respond(&ybApi.ListTablesResponsePB{})
+		}
+	}
+	var never int32
+	leader := testMaster(t, serve("leader", &never, &delayLeader))
+	follower := testMasterWithRole(t, func() ybApi.PeerRole {
+		return ybApi.PeerRole_FOLLOWER
+	}, serve("follower", &rejectReads, &never))
+
+	newClient := func(tt *testing.T, expectedMasters ...string) YBClient {
+		return testClient(tt, &configs.YBClientConfig{
+			MasterHostPort:         []string{leader, follower},
+			OpTimeout:              time.Second * 5,
+			RetryInterval:          time.Millisecond,
+			ReconnectRetryInterval: time.Millisecond,
+			ExpectedMasterUUIDs:    expectedMasters,
+		})
+	}
+	listTables := func(c YBClient, opts ...CallOption) (string, error) {
+		err := c.ExecuteContext(context.Background(), &ybApi.ListTablesRequestPB{}, &ybApi.ListTablesResponsePB{}, opts...)
+		served := ""
+		for {
+			select {
+			case name := <-chanServed:
+				served = name
+			default:
+				return served, err
+			}
+		}
+	}
+
+	t.Run("it=reads from the leader by default", func(tt *testing.T) {
+		c := newClient(tt)
+		served, err := listTables(c)
+		assert.Nil(tt, err)
+		assert.Equal(tt, "leader", served)
+	})
+
+	t.Run("it=reads from the followers", func(tt *testing.T) {
+		c := newClient(tt)
+		served, err := listTables(c, CallWithReadPreference(ReadPreferenceFollower))
+		assert.Nil(tt, err)
+		assert.Equal(tt, "follower", served)
+	})
+
+	t.Run("it=falls back to the leader when the follower rejects the read", func(tt *testing.T) {
+		atomic.StoreInt32(&rejectReads, 1)
+		defer atomic.StoreInt32(&rejectReads, 0)
+		c := newClient(tt)
+		served, err := listTables(c, CallWithReadPreference(ReadPreferenceFollower))
+		assert.Nil(tt, err)
+		assert.Equal(tt, "leader", served)
+	})
+
+	t.Run("it=does not ask followers rejecting reads again until the leader changes", func(tt *testing.T) {
+		atomic.StoreInt32(&rejectReads, 1)
+		defer atomic.StoreInt32(&rejectReads, 0)
+		c := newClient(tt)
+		served, err := listTables(c, CallWithReadPreference(ReadPreferenceFollower))
+		assert.Nil(tt, err)
+		assert.Equal(tt, "leader", served)
+		assert.True(tt, c.(*defaultYBClient).replicas.isRejected(follower))
+		assert.Nil(tt, c.ExecuteContext(context.Background(), &ybApi.ListTablesRequestPB{}, &ybApi.ListTablesResponsePB{},
+			CallWithReadPreference(ReadPreferenceFollower)))
+		assert.Equal(tt, "leader", <-chanServed)
+		assert.Empty(tt, chanServed)
+		c.(*defaultYBClient).lock.Lock()
+		c.(*defaultYBClient).setLeaderUnsafe(leader, c.(*defaultYBClient).connectedClient)
+		c.(*defaultYBClient).lock.Unlock()
+		assert.False(tt, c.(*defaultYBClient).replicas.isRejected(follower))
+	})
+
+	t.Run("it=recognizes the leader under translated and differently cased addresses", func(tt *testing.T) {
+		c := newClient(tt).(*defaultYBClient)
+		c.config.AddressMap = map[string]string{"yb-master-0:7100": leader}
+		assert.True(tt, c.sameMasterAddress("yb-master-0:7100", leader))
+		assert.True(tt, c.sameMasterAddress(leader, "yb-master-0:7100"))
+		assert.True(tt, c.sameMasterAddress("YB-MASTER-1:7100", "yb-master-1:7100"))
+		assert.False(tt, c.sameMasterAddress("yb-master-0:7100", follower))
+		c.lock.Lock()
+		c.discoveredMasters = []string{"yb-master-0:7100"}
+		c.lock.Unlock()
+		for i := 0; i < 2; i++ {
+			served, err := listTables(c, CallWithReadPreference(ReadPreferenceFollower))
+			assert.Nil(tt, err)
+			assert.Equal(tt, "follower", served)
+		}
+		assert.False(tt, c.replicas.isRejected("yb-master-0:7100"))
+	})
+
+	t.Run("it=does not read from unexpected masters", func(tt *testing.T) {
+		c := newClient(tt, leader)
+		served, err := listTables(c, CallWithReadPreference(ReadPreferenceFollower))
+		assert.Nil(tt, err)
+		assert.Equal(tt, "leader", served)
+	})
+
+	t.Run("it=executes writes on the leader", func(tt *testing.T) {
+		c := newClient(tt)
+		err := c.ExecuteContext(context.Background(), &ybApi.TruncateTableRequestPB{}, &ybApi.TruncateTableResponsePB{},
+			CallWithReadPreference(ReadPreferenceFollower), CallWithHedging(time.Millisecond))
+		assert.Nil(tt, err)
+		assert.Equal(tt, "leader", <-chanServed)
+	})
+
+	t.Run("it=reads from the nearest master", func(tt *testing.T) {
+		c := newClient(tt)
+		c.(*defaultYBClient).replicas.observe(leader, time.Second)
+		c.(*defaultYBClient).replicas.observe(follower, time.Millisecond)
+		served, err := listTables(c, CallWithReadPreference(ReadPreferenceNearest))
+		assert.Nil(tt, err)
+		assert.Equal(tt, "follower", served)
+	})
+
+	t.Run("it=hedges slow reads", func(tt *testing.T) {
+		c := newClient(tt)
+		atomic.StoreInt32(&delayLeader, 1)
+		defer atomic.StoreInt32(&delayLeader, 0)
+		started := time.Now()
+		served, err := listTables(c, CallWithHedging(50*time.Millisecond))
+		assert.Nil(tt, err)
+		assert.Equal(tt, "follower", served)
+		assert.Less(tt, int64(time.Since(started)), int64(time.Second))
+	})
+
+}
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+)
+
+// ErrorClass classifies an execute error for the purpose of retries.
+type ErrorClass int
+
+const (
+	// ErrorClassPermanent is an error which can't be recovered from by retrying.
+	ErrorClassPermanent ErrorClass = iota
+	// ErrorClassRetryable is an error which may go away when the call is retried
+	// on the same connection.
+	ErrorClassRetryable
+	// ErrorClassRequiresReconnect is an error which may go away when the call is retried
+	// on a new connection.
+	ErrorClassRequiresReconnect
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassRetryable:
+		return "retryable"
+	case ErrorClassRequiresReconnect:
+		return "requires-reconnect"
+	default:
+		return "permanent"
+	}
+}
+
+// ClassifyError classifies an error returned by the connected client.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassPermanent
+	}
+	// broken pipe qualifies for immediate reconnect:
+	if errors.Is(err, syscall.EPIPE) {
+		return ErrorClassRequiresReconnect
+	}
+	switch tError := err.(type) {
+	case *clientErrors.RequiresReconnectError:
+		return ErrorClassRequiresReconnect
+	case *clientErrors.SendError:
+		// the client was connected but is no longer able to
+		// send data to the server:
+		return ErrorClassRequiresReconnect
+	case *clientErrors.ReceiveError:
+		// could not read payload from the server,
+		// if the connection is still usable, just retry:
+		if tError.RequiresReconnect() {
+			return ErrorClassRequiresReconnect
+		}
+		return ErrorClassRetryable
+	case *clientErrors.UnprocessableResponseError:
+		// complete payload has been read from the server
+		// but payload could not be deserialized as protobuf:
+		return ErrorClassRetryable
+	case *clientErrors.ServiceRPCError:
+		if tError.Cause.Code != nil {
+			switch *tError.Cause.Code {
+			case ybApi.ErrorStatusPB_ERROR_SERVER_TOO_BUSY:
+				return ErrorClassRetryable
+			case ybApi.ErrorStatusPB_FATAL_SERVER_SHUTTING_DOWN:
+				return ErrorClassRequiresReconnect
+			}
+		}
+	}
+	return ErrorClassPermanent
+}
+
+// RetryAction tells the client what to do after a failed attempt.
+type RetryAction int
+
+const (
+	// RetryActionGiveUp returns the error to the caller.
+	RetryActionGiveUp RetryAction = iota
+	// RetryActionRetry retries the call on the same connection.
+	RetryActionRetry
+	// RetryActionReconnect reconnects and retries the call on the new connection.
+	RetryActionReconnect
+)
+
+// RetryDecision is the decision made by the retry policy.
+type RetryDecision struct {
+	Action RetryAction
+	// Delay is the time to wait before the next attempt.
+	Delay time.Duration
+}
+
+// RetryPolicy decides whether a failed call should be retried.
+// Attempts are counted from 1, elapsed is the time since the first attempt.
+type RetryPolicy interface {
+	// Decide is called after every failed execute attempt.
+	Decide(attempt int32, elapsed time.Duration, err error) RetryDecision
+	// DecideReconnect is called after every failed reconnect attempt.
+	// Any action other than RetryActionGiveUp results in another reconnect attempt.
+	DecideReconnect(attempt int32, elapsed time.Duration, err error) RetryDecision
+}
+
+const (
+	// DefaultRetryMultiplier is the default exponential backoff multiplier.
+	DefaultRetryMultiplier = 2.0
+	// DefaultRetryJitter is the default exponential backoff jitter factor.
+	DefaultRetryJitter = 0.2
+)
+
+// ExponentialBackoffRetryPolicy retries retryable errors and reconnects on errors
+// requiring a reconnect with exponentially growing, randomized delays.
+// Zero values of MaxAttempts and MaxReconnectAttempts disable the limit,
+// the NoExecuteRetry and NoReconnectAttempts magic values disable retries and reconnects.
+// A zero Budget means no overall time limit.
+type ExponentialBackoffRetryPolicy struct {
+	MaxAttempts              int32
+	MaxReconnectAttempts     int32
+	InitialInterval          time.Duration
+	ReconnectInitialInterval time.Duration
+	MaxInterval              time.Duration
+	Multiplier               float64
+	Jitter                   float64
+	Budget                   time.Duration
+}
+
+// NewExponentialBackoffRetryPolicy returns an exponential backoff retry policy
+// configured from the client configuration.
+func NewExponentialBackoffRetryPolicy(config *configs.YBClientConfig) *ExponentialBackoffRetryPolicy {
+	return &ExponentialBackoffRetryPolicy{
+		MaxAttempts:              config.MaxExecuteRetries,
+		MaxReconnectAttempts:     config.MaxReconnectAttempts,
+		InitialInterval:          config.RetryInterval,
+		ReconnectInitialInterval: config.ReconnectRetryInterval,
+		MaxInterval:              config.RetryMaxInterval,
+		Multiplier:               DefaultRetryMultiplier,
+		Jitter:                   DefaultRetryJitter,
+		Budget:                   config.RetryBudget,
+	}
+}
+
+// Decide implements RetryPolicy.
+func (p *ExponentialBackoffRetryPolicy) Decide(attempt int32, elapsed time.Duration, err error) RetryDecision {
+	action := RetryActionGiveUp
+	switch ClassifyError(err) {
+	case ErrorClassRetryable:
+		action = RetryActionRetry
+	case ErrorClassRequiresReconnect:
+		if p.MaxReconnectAttempts <= configs.NoReconnectAttempts {
+			return RetryDecision{Action: RetryActionGiveUp}
+		}
+		action = RetryActionReconnect
+	default:
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	if p.MaxAttempts <= configs.NoExecuteRetry {
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	if p.MaxAttempts > 0 && attempt > p.MaxAttempts {
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	// reconnect right away, reconnect attempts are delayed by DecideReconnect:
+	delay := time.Duration(0)
+	if action == RetryActionRetry {
+		delay = p.delay(p.InitialInterval, attempt)
+	}
+	if !p.withinBudget(elapsed, delay) {
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	return RetryDecision{Action: action, Delay: delay}
+}
+
+// DecideReconnect implements RetryPolicy.
+func (p *ExponentialBackoffRetryPolicy) DecideReconnect(attempt int32, elapsed time.Duration, err error) RetryDecision {
+	if p.MaxReconnectAttempts <= configs.NoReconnectAttempts {
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	if p.MaxReconnectAttempts > 0 && attempt >= p.MaxReconnectAttempts {
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	delay := p.delay(p.ReconnectInitialInterval, attempt)
+	if !p.withinBudget(elapsed, delay) {
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	return RetryDecision{Action: RetryActionReconnect, Delay: delay}
+}
+
+func (p *ExponentialBackoffRetryPolicy) delay(initial time.Duration, attempt int32) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		// randomize within [delay - jitter*delay, delay + jitter*delay]:
+		delay = delay + delay*p.Jitter*(rand.Float64()*2-1)
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+func (p *ExponentialBackoffRetryPolicy) withinBudget(elapsed, delay time.Duration) bool {
+	return p.Budget <= 0 || elapsed+delay <= p.Budget
+}
+
+// CallOption configures a single call.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	hedgingDelay   time.Duration
+	readPreference ReadPreference
+	retryPolicy    RetryPolicy
+	serviceInfo    ServiceInfo
+	sidecars       *Sidecars
+}
+
+func newCallOptions(opts ...CallOption) *callOptions {
+	options := &callOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// callWithServiceInfo executes the call with the service and method
+// instead of the ones registered for the payload type.
+func callWithServiceInfo(svcInfo ServiceInfo) CallOption {
+	return func(o *callOptions) {
+		o.serviceInfo = svcInfo
+	}
+}
+
+// CallWithRetryPolicy overrides the client retry policy for a single call.
+func CallWithRetryPolicy(policy RetryPolicy) CallOption {
+	return func(o *callOptions) {
+		o.retryPolicy = policy
+	}
+}
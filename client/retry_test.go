@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCountingRetryPolicy struct {
+	lock     *sync.Mutex
+	attempts int
+	retries  int
+}
+
+func (p *testCountingRetryPolicy) Decide(attempt int32, elapsed time.Duration, err error) RetryDecision {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.attempts = p.attempts + 1
+	if int(attempt) > p.retries {
+		return RetryDecision{Action: RetryActionGiveUp}
+	}
+	return RetryDecision{Action: RetryActionRetry}
+}
+
+func (p *testCountingRetryPolicy) DecideReconnect(attempt int32, elapsed time.Duration, err error) RetryDecision {
+	return RetryDecision{Action: RetryActionGiveUp}
+}
+
+func TestClassifyError(t *testing.T) {
+	for _, testCase := range []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{err: nil, expected: ErrorClassPermanent},
+		{err: fmt.Errorf("other"), expected: ErrorClassPermanent},
+		{err: fmt.Errorf("wrapped: %w", syscall.EPIPE), expected: ErrorClassRequiresReconnect},
+		{err: &clientErrors.SendError{Cause: io.EOF}, expected: ErrorClassRequiresReconnect},
+		{err: &clientErrors.ReceiveError{Cause: io.EOF}, expected: ErrorClassRequiresReconnect},
+		{err: &clientErrors.ReceiveError{Cause: fmt.Errorf("header")}, expected: ErrorClassRetryable},
+		{err: &clientErrors.ReceiveError{Cause: &clientErrors.ConnectionClosedError{Cause: syscall.ECONNRESET}}, expected: ErrorClassRequiresReconnect},
+		{err: &clientErrors.RequiresReconnectError{Cause: fmt.Errorf("leader")}, expected: ErrorClassRequiresReconnect},
+		{err: &clientErrors.UnprocessableResponseError{Cause: fmt.Errorf("proto")}, expected: ErrorClassRetryable},
+		{err: &clientErrors.ServiceRPCError{Cause: &ybApi.ErrorStatusPB{
+			Code: ybApi.ErrorStatusPB_ERROR_SERVER_TOO_BUSY.Enum(),
+		}}, expected: ErrorClassRetryable},
+		{err: &clientErrors.ServiceRPCError{Cause: &ybApi.ErrorStatusPB{
+			Code: ybApi.ErrorStatusPB_FATAL_SERVER_SHUTTING_DOWN.Enum(),
+		}}, expected: ErrorClassRequiresReconnect},
+		{err: &clientErrors.ServiceRPCError{Cause: &ybApi.ErrorStatusPB{
+			Code: ybApi.ErrorStatusPB_ERROR_NO_SUCH_METHOD.Enum(),
+		}}, expected: ErrorClassPermanent},
+	} {
+		assert.Equal(t, testCase.expected, ClassifyError(testCase.err), fmt.Sprintf("%v", testCase.err))
+	}
+}
+
+func TestExponentialBackoffRetryPolicy(t *testing.T) {
+
+	retryableErr := &clientErrors.UnprocessableResponseError{Cause: fmt.Errorf("proto")}
+	reconnectErr := &clientErrors.SendError{Cause: io.EOF}
+
+	t.Run("it=grows delays up to the maximum interval", func(tt *testing.T) {
+		policy := &ExponentialBackoffRetryPolicy{
+			InitialInterval: time.Millisecond * 100,
+			MaxInterval:     time.Millisecond * 500,
+			Multiplier:      2,
+		}
+		expected := []time.Duration{100, 200, 400, 500, 500}
+		for idx, exp := range expected {
+			decision := policy.Decide(int32(idx+1), 0, retryableErr)
+			assert.Equal(tt, RetryActionRetry, decision.Action)
+			assert.Equal(tt, exp*time.Millisecond, decision.Delay)
+		}
+	})
+
+	t.Run("it=randomizes delays within jitter bounds", func(tt *testing.T) {
+		policy := &ExponentialBackoffRetryPolicy{
+			InitialInterval: time.Second,
+			Multiplier:      2,
+			Jitter:          0.5,
+		}
+		for i := 0; i < 100; i = i + 1 {
+			decision := policy.Decide(2, 0, retryableErr)
+			assert.GreaterOrEqual(tt, int64(decision.Delay), int64(time.Second))
+			assert.LessOrEqual(tt, int64(decision.Delay), int64(time.Second*3))
+		}
+	})
+
+	t.Run("it=gives up after maximum attempts and outside of the budget", func(tt *testing.T) {
+		policy := &ExponentialBackoffRetryPolicy{
+			MaxAttempts:     3,
+			InitialInterval: time.Second,
+			Multiplier:      1,
+			Budget:          time.Second * 10,
+		}
+		assert.Equal(tt, RetryActionRetry, policy.Decide(3, 0, retryableErr).Action)
+		assert.Equal(tt, RetryActionGiveUp, policy.Decide(4, 0, retryableErr).Action)
+		assert.Equal(tt, RetryActionRetry, policy.Decide(1, time.Second*9, retryableErr).Action)
+		assert.Equal(tt, RetryActionGiveUp, policy.Decide(1, time.Second*9+1, retryableErr).Action)
+	})
+
+	t.Run("it=classifies errors", func(tt *testing.T) {
+		policy := NewExponentialBackoffRetryPolicy((&configs.YBClientConfig{}).WithDefaults())
+		assert.Equal(tt, RetryActionReconnect, policy.Decide(1, 0, reconnectErr).Action)
+		assert.Equal(tt, time.Duration(0), policy.Decide(1, 0, reconnectErr).Delay)
+		assert.Equal(tt, RetryActionGiveUp, policy.Decide(1, 0, fmt.Errorf("permanent")).Action)
+		assert.Equal(tt, RetryActionReconnect, policy.DecideReconnect(1, 0, io.EOF).Action)
+		assert.Equal(tt, RetryActionGiveUp, policy.DecideReconnect(configs.DefaultMaxReconnectAttempts, 0, io.EOF).Action)
+	})
+
+	t.Run("it=honors disabled retries and reconnects", func(tt *testing.T) {
+		policy := NewExponentialBackoffRetryPolicy((&configs.YBClientConfig{
+			MaxExecuteRetries:    configs.NoExecuteRetry,
+			MaxReconnectAttempts: configs.NoReconnectAttempts,
+		}).WithDefaults())
+		assert.Equal(tt, RetryActionGiveUp, policy.Decide(1, 0, retryableErr).Action)
+		assert.Equal(tt, RetryActionGiveUp, policy.Decide(1, 0, reconnectErr).Action)
+		assert.Equal(tt, RetryActionGiveUp, policy.DecideReconnect(1, 0, io.EOF).Action)
+	})
+
+}
+
+func TestClientRetryPolicy(t *testing.T) {
+
+	address := testMaster(t, func(request *testRequest) {
+		// not a valid protobuf payload:
+		request.respondBytes([]byte{0xff, 0xff, 0xff})
+	})
+
+	clientPolicy := &testCountingRetryPolicy{lock: &sync.Mutex{}, retries: 2}
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{address},
+		OpTimeout:      time.Second,
+	}).WithRetryPolicy(clientPolicy)
+
+	t.Run("it=uses the client retry policy", func(tt *testing.T) {
+		err := c.ExecuteContext(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.IsType(tt, &clientErrors.UnprocessableResponseError{}, err)
+		assert.Equal(tt, 3, clientPolicy.attempts)
+	})
+
+	t.Run("it=uses the call retry policy", func(tt *testing.T) {
+		callPolicy := &testCountingRetryPolicy{lock: &sync.Mutex{}, retries: 4}
+		err := c.ExecuteContext(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{},
+			CallWithRetryPolicy(callPolicy))
+		assert.IsType(tt, &clientErrors.UnprocessableResponseError{}, err)
+		assert.Equal(tt, 5, callPolicy.attempts)
+		assert.Equal(tt, 3, clientPolicy.attempts)
+	})
+
+}
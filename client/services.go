@@ -1,11 +1,14 @@
 package client
 
 import (
-	"strings"
+	"sort"
+	"sync"
 
 	"github.com/radekg/yugabyte-db-go-client/utils"
 	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -64,49 +67,122 @@ func loadServiceDefinitions(registry ServiceRegistry) {
 	}
 
 	for _, fd := range descriptors {
-		loadServiceDescriptor(registry, fd)
+		loadServiceDescriptor(func(svcName string, method protoreflect.MethodDescriptor) {
+			registry.Register(string(method.Input().FullName()), string(method.Name()), svcName)
+		}, fd)
 	}
 }
 
-func loadServiceDescriptor(registry ServiceRegistry, descriptor protoreflect.FileDescriptor) {
+// loadServiceDescriptor registers the methods of the services of the file with the register function.
+func loadServiceDescriptor(register func(svcName string, method protoreflect.MethodDescriptor), descriptor protoreflect.FileDescriptor) {
 	services := descriptor.Services()
 	for i := 0; i < services.Len(); i = i + 1 {
 
 		svcDescriptor := services.Get(i)
 
-		// discover the service name:
-		// in v2.11.2.0, the protobuf service may be optionally annotated
-		// with a custom option, for example:
-		// master/master_cluster.proto
-		// ---------------------------
-		//
-		// service MasterCluster {
-		//  option (yb.rpc.custom_service_name) = "yb.master.MasterService";
-		//
-		// ---------------------------
-		// Here we look up if that option exists, if yes
-		// we use it instead of the default service name.
-
-		svcName := string(svcDescriptor.FullName())
-		if opts := svcDescriptor.Options(); opts != nil {
-			if topts, ok := opts.(*descriptorpb.ServiceOptions); ok && topts != nil {
-				topts.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
-					if string(fd.FullName()) == "yb.rpc.custom_service_name" {
-						svcName = v.String()
-					}
-					return true
-				})
-			}
-		}
+		svcName := serviceName(svcDescriptor)
 
 		methods := svcDescriptor.Methods()
 		for j := 0; j < methods.Len(); j = j + 1 {
-			method := methods.Get(j)
-			methodNameParts := strings.Split(string(method.FullName()), ".")
-			opName := methodNameParts[len(methodNameParts)-1]
-			registry.Register(string(method.Input().FullName()), opName, svcName)
+			register(svcName, methods.Get(j))
+		}
+	}
+}
+
+// serviceName returns the service name used in the request header.
+func serviceName(svcDescriptor protoreflect.ServiceDescriptor) string {
+	// discover the service name:
+	// in v2.11.2.0, the protobuf service may be optionally annotated
+	// with a custom option, for example:
+	// master/master_cluster.proto
+	// ---------------------------
+	//
+	// service MasterCluster {
+	//  option (yb.rpc.custom_service_name) = "yb.master.MasterService";
+	//
+	// ---------------------------
+	// Here we look up if that option exists, if yes
+	// we use it instead of the default service name.
+
+	svcName := string(svcDescriptor.FullName())
+	if opts := svcDescriptor.Options(); opts != nil {
+		if topts, ok := opts.(*descriptorpb.ServiceOptions); ok && topts != nil {
+			if len(topts.ProtoReflect().GetUnknown()) > 0 {
+				// options of runtime loaded descriptors may have been parsed
+				// without the YugabyteDB extensions:
+				topts = resolveServiceOptions(topts)
+			}
+			topts.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+				if string(fd.FullName()) == "yb.rpc.custom_service_name" {
+					svcName = v.String()
+				}
+				return true
+			})
+		}
+	}
+	return svcName
+}
+
+// resolveServiceOptions parses the service options again with the extensions
+// registered in the global registry.
+func resolveServiceOptions(opts *descriptorpb.ServiceOptions) *descriptorpb.ServiceOptions {
+	serialized, err := proto.Marshal(opts)
+	if err != nil {
+		return opts
+	}
+	resolved := &descriptorpb.ServiceOptions{}
+	if err := (proto.UnmarshalOptions{Resolver: protoregistry.GlobalTypes}).Unmarshal(serialized, resolved); err != nil {
+		return opts
+	}
+	return resolved
+}
+
+// LookupInputType returns the input message descriptor of the method
+// of a service registered in the global protobuf registry.
+// The service name is the name used in the request header.
+func LookupInputType(service, method string) (protoreflect.MessageDescriptor, bool) {
+	methodDescriptor, ok := LookupMethod(service, method)
+	if !ok {
+		return nil, false
+	}
+	return methodDescriptor.Input(), true
+}
+
+// LookupMethod returns the method descriptor of the method
+// of a service registered in the global protobuf registry.
+// The service name is the name used in the request header
+// or the full name of the protobuf service.
+func LookupMethod(service, method string) (protoreflect.MethodDescriptor, bool) {
+	return lookupMethod(protoregistry.GlobalFiles, service, method)
+}
+
+func lookupMethod(files *protoregistry.Files, service, method string) (protoreflect.MethodDescriptor, bool) {
+	var result protoreflect.MethodDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := 0; i < services.Len(); i = i + 1 {
+			svcDescriptor := services.Get(i)
+			if serviceName(svcDescriptor) != service && string(svcDescriptor.FullName()) != service {
+				continue
+			}
+			if methodDescriptor := svcDescriptor.Methods().ByName(protoreflect.Name(method)); methodDescriptor != nil {
+				result = methodDescriptor
+				return false
+			}
 		}
+		return true
+	})
+	return result, result != nil
+}
+
+// resolveServiceInfo returns the service info given in the call options,
+// otherwise the service info registered for the payload type.
+// Returns nil if the payload type is not registered.
+func resolveServiceInfo(registry ServiceRegistry, payload protoreflect.ProtoMessage, options *callOptions) ServiceInfo {
+	if options.serviceInfo != nil {
+		return options.serviceInfo
 	}
+	return registry.Get(payload)
 }
 
 // ServiceInfo contains the service and method names used
@@ -117,6 +193,15 @@ type ServiceInfo interface {
 	ToRemoteMethodPB() *ybApi.RemoteMethodPB
 }
 
+// NewServiceInfo returns the service info for the service and method,
+// the service is the name used in the request header.
+func NewServiceInfo(service, method string) ServiceInfo {
+	return &defaultServiceInfo{
+		method:  method,
+		service: service,
+	}
+}
+
 type defaultServiceInfo struct {
 	method  string
 	service string
@@ -137,34 +222,229 @@ func (i *defaultServiceInfo) ToRemoteMethodPB() *ybApi.RemoteMethodPB {
 	}
 }
 
+// MethodInfo describes a method registered in the service registry.
+type MethodInfo interface {
+	ServiceInfo
+	// InputType returns the full name of the request message.
+	InputType() protoreflect.FullName
+	// OutputType returns the full name of the response message,
+	// empty if the method is not defined by any known file descriptor.
+	OutputType() protoreflect.FullName
+}
+
+type defaultMethodInfo struct {
+	defaultServiceInfo
+	inputType  protoreflect.FullName
+	outputType protoreflect.FullName
+}
+
+func (i *defaultMethodInfo) InputType() protoreflect.FullName {
+	return i.inputType
+}
+
+func (i *defaultMethodInfo) OutputType() protoreflect.FullName {
+	return i.outputType
+}
+
 // ServiceRegistry contains the information about registered services and inputs.
+// A service registry may be shared by multiple clients and must be safe for concurrent use.
 type ServiceRegistry interface {
+	// Get returns the service info of the method registered last for the input type.
 	Get(t protoreflect.ProtoMessage) ServiceInfo
 	Register(inputTypeName, methodName, svcName string)
+	// Candidates returns all methods registered for the input type in the registration order.
+	// Use ExecuteMethod to call a method other than the one returned by Get.
+	Candidates(t protoreflect.ProtoMessage) []MethodInfo
+	// Duplicates returns the candidates of the input types registered for more than one method.
+	Duplicates() map[protoreflect.FullName][]MethodInfo
+	// LoadFileDescriptorSet registers the services of the file descriptor set.
+	// Services and messages of the set override the previously registered ones,
+	// imports missing in the set are resolved from the previously loaded sets
+	// and the compiled in definitions.
+	LoadFileDescriptorSet(set *descriptorpb.FileDescriptorSet) error
+	// LookupMethod returns the method descriptor of the method of the service,
+	// the service is the name used in the request header or the full name of the protobuf service.
+	// The file descriptor sets are searched in the reverse load order before the compiled in definitions.
+	LookupMethod(service, method string) (protoreflect.MethodDescriptor, bool)
+	// Methods returns all registered methods sorted by the service and method name.
+	Methods() []MethodInfo
 }
 
 type defaultServiceRegistry struct {
-	registry map[string]ServiceInfo
+	files []*protoregistry.Files
+	lock  *sync.RWMutex
+	// registry holds the methods registered for the input type in the registration order:
+	registry map[protoreflect.FullName][]*defaultMethodInfo
 }
 
 // NewDefaultServiceRegistry returns an initialized
 // instance of the default service registry.
 func NewDefaultServiceRegistry() ServiceRegistry {
 	return &defaultServiceRegistry{
-		registry: map[string]ServiceInfo{},
+		lock:     &sync.RWMutex{},
+		registry: map[protoreflect.FullName][]*defaultMethodInfo{},
 	}
 }
 
+// NewLoadedServiceRegistry returns a default service registry
+// with all YugabyteDB service definitions loaded.
+func NewLoadedServiceRegistry() ServiceRegistry {
+	registry := NewDefaultServiceRegistry()
+	loadServiceDefinitions(registry)
+	return registry
+}
+
 func (r *defaultServiceRegistry) Get(t protoreflect.ProtoMessage) ServiceInfo {
-	if v, ok := r.registry[string(t.ProtoReflect().Descriptor().FullName())]; ok {
-		return v
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if candidates := r.registry[t.ProtoReflect().Descriptor().FullName()]; len(candidates) > 0 {
+		return candidates[len(candidates)-1]
 	}
 	return nil
 }
 
 func (r *defaultServiceRegistry) Register(inputTypeName, methodName, svcName string) {
-	r.registry[inputTypeName] = &defaultServiceInfo{
-		method:  methodName,
-		service: svcName,
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.registerUnsafe(protoreflect.FullName(inputTypeName), methodName, svcName, "")
+}
+
+func (r *defaultServiceRegistry) registerUnsafe(inputType protoreflect.FullName, methodName, svcName string, outputType protoreflect.FullName) {
+	candidates := []*defaultMethodInfo{}
+	for _, candidate := range r.registry[inputType] {
+		// registering the method again moves it to the end:
+		if candidate.Service() != svcName || candidate.Method() != methodName {
+			candidates = append(candidates, candidate)
+		}
+	}
+	r.registry[inputType] = append(candidates, &defaultMethodInfo{
+		defaultServiceInfo: defaultServiceInfo{
+			method:  methodName,
+			service: svcName,
+		},
+		inputType:  inputType,
+		outputType: outputType,
+	})
+}
+
+func (r *defaultServiceRegistry) Candidates(t protoreflect.ProtoMessage) []MethodInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.methodInfosUnsafe(r.registry[t.ProtoReflect().Descriptor().FullName()], r.methodIndexUnsafe())
+}
+
+func (r *defaultServiceRegistry) Duplicates() map[protoreflect.FullName][]MethodInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	result := map[protoreflect.FullName][]MethodInfo{}
+	var index map[string]protoreflect.MethodDescriptor
+	for inputType, candidates := range r.registry {
+		if len(candidates) < 2 {
+			continue
+		}
+		if index == nil {
+			index = r.methodIndexUnsafe()
+		}
+		result[inputType] = r.methodInfosUnsafe(candidates, index)
+	}
+	return result
+}
+
+func (r *defaultServiceRegistry) Methods() []MethodInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	index := r.methodIndexUnsafe()
+	result := []MethodInfo{}
+	for _, candidates := range r.registry {
+		result = append(result, r.methodInfosUnsafe(candidates, index)...)
+	}
+	sortMethodInfos(result)
+	return result
+}
+
+// sortMethodInfos sorts the methods by the service and method name.
+func sortMethodInfos(infos []MethodInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Service() != infos[j].Service() {
+			return infos[i].Service() < infos[j].Service()
+		}
+		if infos[i].Method() != infos[j].Method() {
+			return infos[i].Method() < infos[j].Method()
+		}
+		return infos[i].InputType() < infos[j].InputType()
+	})
+}
+
+// methodInfosUnsafe returns copies of the method infos with the output type
+// of the methods registered without one resolved from the method index.
+func (r *defaultServiceRegistry) methodInfosUnsafe(candidates []*defaultMethodInfo, index map[string]protoreflect.MethodDescriptor) []MethodInfo {
+	result := []MethodInfo{}
+	for _, candidate := range candidates {
+		info := *candidate
+		if info.outputType == "" {
+			if method, ok := index[methodIndexKey(info.Service(), info.Method())]; ok && method.Input().FullName() == info.inputType {
+				info.outputType = method.Output().FullName()
+			}
+		}
+		result = append(result, &info)
+	}
+	return result
+}
+
+// methodIndexUnsafe indexes the methods of all known file descriptors
+// by the header service name and the method name.
+// The file descriptor sets take precedence over the compiled in definitions.
+func (r *defaultServiceRegistry) methodIndexUnsafe() map[string]protoreflect.MethodDescriptor {
+	index := map[string]protoreflect.MethodDescriptor{}
+	add := func(files *protoregistry.Files) {
+		files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			loadServiceDescriptor(func(svcName string, method protoreflect.MethodDescriptor) {
+				if _, ok := index[methodIndexKey(svcName, string(method.Name()))]; !ok {
+					index[methodIndexKey(svcName, string(method.Name()))] = method
+				}
+			}, fd)
+			return true
+		})
+	}
+	for i := len(r.files) - 1; i >= 0; i = i - 1 {
+		add(r.files[i])
+	}
+	add(protoregistry.GlobalFiles)
+	return index
+}
+
+func methodIndexKey(service, method string) string {
+	return service + "/" + method
+}
+
+func (r *defaultServiceRegistry) LoadFileDescriptorSet(set *descriptorpb.FileDescriptorSet) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	fallback := []*protoregistry.Files{}
+	for i := len(r.files) - 1; i >= 0; i = i - 1 {
+		fallback = append(fallback, r.files[i])
+	}
+	files, err := newFilesFromSet(set, append(fallback, protoregistry.GlobalFiles)...)
+	if err != nil {
+		return err
+	}
+	r.files = append(r.files, files)
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		loadServiceDescriptor(func(svcName string, method protoreflect.MethodDescriptor) {
+			r.registerUnsafe(method.Input().FullName(), string(method.Name()), svcName, method.Output().FullName())
+		}, fd)
+		return true
+	})
+	return nil
+}
+
+func (r *defaultServiceRegistry) LookupMethod(service, method string) (protoreflect.MethodDescriptor, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for i := len(r.files) - 1; i >= 0; i = i - 1 {
+		if methodDescriptor, ok := lookupMethod(r.files[i], service, method); ok {
+			return methodDescriptor, true
+		}
 	}
+	return LookupMethod(service, method)
 }
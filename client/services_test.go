@@ -37,3 +37,81 @@ func TestServiceDescribe(t *testing.T) {
 	})
 
 }
+
+func TestLookupMethod(t *testing.T) {
+
+	t.Run("it=resolves methods by the header service name", func(tt *testing.T) {
+		method, ok := LookupMethod("yb.master.MasterService", "ListTables")
+		if assert.True(tt, ok) {
+			assert.Equal(tt, (&ybApi.ListTablesRequestPB{}).ProtoReflect().Descriptor().FullName(), method.Input().FullName())
+			assert.Equal(tt, (&ybApi.ListTablesResponsePB{}).ProtoReflect().Descriptor().FullName(), method.Output().FullName())
+		}
+	})
+
+	t.Run("it=resolves methods by the protobuf service name", func(tt *testing.T) {
+		method, ok := LookupMethod("yb.tserver.TabletServerService", "IsTabletServerReady")
+		if assert.True(tt, ok) {
+			assert.Equal(tt, (&ybApi.IsTabletServerReadyRequestPB{}).ProtoReflect().Descriptor().FullName(), method.Input().FullName())
+		}
+	})
+
+	t.Run("it=does not resolve unknown methods", func(tt *testing.T) {
+		_, ok := LookupMethod("yb.master.MasterService", "NoSuchMethod")
+		assert.False(tt, ok)
+		_, ok = LookupInputType("no.such.Service", "ListTables")
+		assert.False(tt, ok)
+	})
+
+}
+
+func TestServiceRegistryCandidates(t *testing.T) {
+
+	svcRegistry := NewLoadedServiceRegistry()
+
+	t.Run("it=exposes all methods sharing the input type", func(tt *testing.T) {
+		candidates := svcRegistry.Candidates(&ybApi.WriteRequestPB{})
+		if assert.Equal(tt, 2, len(candidates)) {
+			assert.Equal(tt, "yb.tserver.TabletServerForwardService", candidates[0].Service())
+			assert.Equal(tt, "yb.tserver.TabletServerService", candidates[1].Service())
+			for _, candidate := range candidates {
+				assert.Equal(tt, "Write", candidate.Method())
+				assert.Equal(tt, (&ybApi.WriteResponsePB{}).ProtoReflect().Descriptor().FullName(), candidate.OutputType())
+			}
+		}
+		svcInfo := svcRegistry.Get(&ybApi.WriteRequestPB{})
+		if assert.NotNil(tt, svcInfo) {
+			assert.Equal(tt, "yb.tserver.TabletServerService", svcInfo.Service())
+		}
+		duplicates := svcRegistry.Duplicates()
+		assert.Equal(tt, 2, len(duplicates[(&ybApi.WriteRequestPB{}).ProtoReflect().Descriptor().FullName()]))
+		assert.NotContains(tt, duplicates, (&ybApi.ListMastersRequestPB{}).ProtoReflect().Descriptor().FullName())
+	})
+
+	t.Run("it=moves methods registered again to the end", func(tt *testing.T) {
+		registry := NewDefaultServiceRegistry()
+		registry.Register("yb.master.ListMastersRequestPB", "ListMasters", "yb.master.MasterService")
+		registry.Register("yb.master.ListMastersRequestPB", "ListMasters", "yb.master.OtherService")
+		assert.Equal(tt, "yb.master.OtherService", registry.Get(&ybApi.ListMastersRequestPB{}).Service())
+		registry.Register("yb.master.ListMastersRequestPB", "ListMasters", "yb.master.MasterService")
+		assert.Equal(tt, "yb.master.MasterService", registry.Get(&ybApi.ListMastersRequestPB{}).Service())
+		assert.Equal(tt, 2, len(registry.Candidates(&ybApi.ListMastersRequestPB{})))
+	})
+
+	t.Run("it=lists all registered methods", func(tt *testing.T) {
+		methods := svcRegistry.Methods()
+		var listMasters MethodInfo
+		for i, method := range methods {
+			if i > 0 {
+				assert.LessOrEqual(tt, methods[i-1].Service(), method.Service())
+			}
+			if method.Service() == "yb.master.MasterService" && method.Method() == "ListMasters" {
+				listMasters = method
+			}
+		}
+		if assert.NotNil(tt, listMasters) {
+			assert.Equal(tt, (&ybApi.ListMastersRequestPB{}).ProtoReflect().Descriptor().FullName(), listMasters.InputType())
+			assert.Equal(tt, (&ybApi.ListMastersResponsePB{}).ProtoReflect().Descriptor().FullName(), listMasters.OutputType())
+		}
+	})
+
+}
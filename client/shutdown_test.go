@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown(t *testing.T) {
+
+	chanRelease := make(chan struct{})
+	defer close(chanRelease)
+	address := testMaster(t, func(request *testRequest) {
+		<-chanRelease
+		request.respond(&ybApi.ListMastersResponsePB{})
+	})
+
+	t.Run("it=waits for the calls in progress", func(tt *testing.T) {
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second * 5,
+		})
+		future := c.ExecuteAsync(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		<-time.After(50 * time.Millisecond)
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancelFunc()
+		chanShutdown := make(chan error, 1)
+		go func() {
+			chanShutdown <- c.Shutdown(ctx)
+		}()
+		for c.State() != StateShuttingDown {
+			<-time.After(time.Millisecond)
+		}
+
+		err := c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.True(tt, errors.Is(err, clientErrors.ErrClosed))
+		assert.IsType(tt, &clientErrors.ClosedError{}, err)
+
+		chanRelease <- struct{}{}
+		assert.Nil(tt, future.Wait(context.Background()))
+		assert.Nil(tt, <-chanShutdown)
+		assert.Equal(tt, StateClosed, c.State())
+	})
+
+	t.Run("it=closes when the context is done", func(tt *testing.T) {
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second * 5,
+		})
+		future := c.ExecuteAsync(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		<-time.After(50 * time.Millisecond)
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancelFunc()
+		assert.Equal(tt, context.DeadlineExceeded, c.Shutdown(ctx))
+		assert.Equal(tt, StateClosed, c.State())
+		assert.Error(tt, future.Wait(context.Background()))
+	})
+
+	t.Run("it=warns only when closing with calls in progress", func(tt *testing.T) {
+		output := &bytes.Buffer{}
+		logger := hclog.New(&hclog.LoggerOptions{Output: output, Level: hclog.Warn})
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second * 5,
+		}, func(c YBClient) YBClient {
+			return c.WithLogger(logger)
+		})
+		assert.Nil(tt, c.Execute(&ybApi.GetMasterRegistrationRequestPB{}, &ybApi.GetMasterRegistrationResponsePB{}))
+		assert.Nil(tt, c.Close())
+		assert.NotContains(tt, output.String(), "closing with calls in progress")
+
+		c = testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second * 5,
+		}, func(c YBClient) YBClient {
+			return c.WithLogger(logger)
+		})
+		future := c.ExecuteAsync(context.Background(), &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		<-time.After(50 * time.Millisecond)
+		assert.Nil(tt, c.Close())
+		assert.Error(tt, future.Wait(context.Background()))
+		assert.Contains(tt, output.String(), "closing with calls in progress")
+	})
+
+	t.Run("it=closes idempotently", func(tt *testing.T) {
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second,
+		})
+		assert.Nil(tt, c.Close())
+		assert.Nil(tt, c.Close())
+		assert.Nil(tt, c.Shutdown(context.Background()))
+		assert.Equal(tt, clientErrors.ErrClosed, c.Connect())
+
+		notConnected := NewYBClient(&configs.YBClientConfig{
+			MasterHostPort: []string{address},
+		}).WithLogger(hclog.NewNullLogger())
+		assert.Nil(tt, notConnected.Close())
+		assert.Equal(tt, StateClosed, notConnected.State())
+	})
+
+}
+
+func TestShutdownCancelsReconnects(t *testing.T) {
+
+	var follower int32
+	address := testMasterWithRole(t, func() ybApi.PeerRole {
+		if atomic.LoadInt32(&follower) == 1 {
+			return ybApi.PeerRole_FOLLOWER
+		}
+		return ybApi.PeerRole_LEADER
+	}, func(request *testRequest) {
+		if request.header.GetRemoteMethod().GetMethodName() == "ListMasters" {
+			request.respond(&ybApi.ListMastersResponsePB{})
+			return
+		}
+		request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort:         []string{address},
+		OpTimeout:              time.Second,
+		ReconnectRetryInterval: time.Minute,
+	})
+
+	t.Run("it=fails the reconnecting call with closed", func(tt *testing.T) {
+		atomic.StoreInt32(&follower, 1)
+		future := c.ExecuteAsync(context.Background(), &ybApi.ListTablesRequestPB{}, &ybApi.ListTablesResponsePB{})
+		for c.State() != StateReconnecting {
+			<-time.After(time.Millisecond)
+		}
+		<-time.After(50 * time.Millisecond)
+
+		started := time.Now()
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancelFunc()
+		assert.Nil(tt, c.Shutdown(ctx))
+		assert.Less(tt, int64(time.Since(started)), int64(time.Second*2))
+		assert.True(tt, errors.Is(future.Wait(context.Background()), clientErrors.ErrClosed))
+	})
+
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Sidecars holds the sidecars of a response.
+// Tablet server data-plane calls, for example Read, return the row data
+// in sidecars following the response message.
+type Sidecars struct {
+	lock *sync.Mutex
+	data [][]byte
+}
+
+// NewSidecars returns an empty sidecar holder.
+func NewSidecars() *Sidecars {
+	return &Sidecars{lock: &sync.Mutex{}}
+}
+
+// Len returns the number of sidecars.
+func (s *Sidecars) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.data)
+}
+
+// Get returns the sidecar at the index, the index is the sidecar index
+// referenced by the response message. Returns an error if there is no such sidecar.
+func (s *Sidecars) Get(index int) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if index < 0 || index >= len(s.data) {
+		return nil, fmt.Errorf("sidecar index %d out of range, response has %d sidecars", index, len(s.data))
+	}
+	return s.data[index], nil
+}
+
+// All returns all sidecars in order.
+func (s *Sidecars) All() [][]byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([][]byte{}, s.data...)
+}
+
+func (s *Sidecars) set(data [][]byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data = data
+}
+
+// CallWithSidecars fills the holder with the sidecars of the response.
+// The holder is replaced on every attempt, after the call returns
+// it contains the sidecars of the last received response.
+func CallWithSidecars(holder *Sidecars) CallOption {
+	return func(o *callOptions) {
+		o.sidecars = holder
+	}
+}
+
+// splitSidecars splits the response payload into the response message
+// and the sidecars. The sidecar offsets are counted from the first byte
+// of the response message, the first offset is the end of the message.
+func splitSidecars(payload []byte, offsets []uint32) ([]byte, [][]byte, error) {
+	if len(offsets) == 0 {
+		return payload, [][]byte{}, nil
+	}
+	sidecars := make([][]byte, 0, len(offsets))
+	for i, offset := range offsets {
+		end := uint32(len(payload))
+		if i < len(offsets)-1 {
+			end = offsets[i+1]
+		}
+		if offset > end || end > uint32(len(payload)) {
+			return nil, nil, fmt.Errorf("invalid sidecar %d bounds %d:%d, payload has %d bytes", i, offset, end, len(payload))
+		}
+		sidecars = append(sidecars, payload[offset:end])
+	}
+	return payload[0:offsets[0]], sidecars, nil
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// testRespondWithSidecars responds with the message followed by the sidecars,
+// the message length includes the sidecars.
+func testRespondWithSidecars(request *testRequest, offsets []uint32, response protoreflect.ProtoMessage, sidecars ...[]byte) {
+	message, _ := utils.SerializeProto(response)
+	if offsets == nil {
+		for _, sidecar := range sidecars {
+			offsets = append(offsets, uint32(len(message)))
+			message = append(message, sidecar...)
+		}
+	} else {
+		for _, sidecar := range sidecars {
+			message = append(message, sidecar...)
+		}
+	}
+	header, _ := utils.SerializeProto(&ybApi.ResponseHeader{
+		CallId:         utils.PInt32(request.header.GetCallId()),
+		IsError:        utils.PBool(false),
+		SidecarOffsets: offsets,
+	})
+	b := bytes.NewBuffer([]byte{})
+	for _, part := range [][]byte{header, message} {
+		varint := make([]byte, binary.MaxVarintLen32)
+		b.Write(varint[0:binary.PutUvarint(varint, uint64(len(part)))])
+		b.Write(part)
+	}
+	length := make([]byte, wire.FrameLengthSize)
+	binary.BigEndian.PutUint32(length, uint32(b.Len()))
+	request.respondFrame(append(length, b.Bytes()...))
+}
+
+func TestSidecars(t *testing.T) {
+
+	var calls int32
+	address := testMaster(t, func(request *testRequest) {
+		readRequest := &ybApi.ReadRequestPB{}
+		utils.DeserializeProto(request.payload, readRequest)
+		response := &ybApi.ReadResponsePB{
+			PgsqlBatch: []*ybApi.PgsqlResponsePB{
+				{RowsDataSidecar: utils.PInt32(1)},
+			},
+		}
+		switch string(readRequest.GetTabletId()) {
+		case "invalid":
+			testRespondWithSidecars(request, []uint32{1000}, response, []byte("rows"))
+		case "retry":
+			// the first call returns sidecars, the second call none:
+			if atomic.AddInt32(&calls, 1) == 1 {
+				testRespondWithSidecars(request, nil, &ybApi.ReadResponsePB{
+					Error: &ybApi.TabletServerErrorPB{
+						Code: ybApi.TabletServerErrorPB_NOT_THE_LEADER.Enum(),
+						Status: &ybApi.AppStatusPB{
+							Code: ybApi.AppStatusPB_ILLEGAL_STATE.Enum(),
+						},
+					},
+				}, []byte("stale"))
+				return
+			}
+			testRespondWithSidecars(request, nil, response)
+		default:
+			testRespondWithSidecars(request, nil, response, []byte("first"), []byte("rows"))
+		}
+	})
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort:    []string{address},
+		OpTimeout:         time.Second,
+		MaxExecuteRetries: configs.NoExecuteRetry,
+	})
+
+	t.Run("it=returns sidecars with the response", func(tt *testing.T) {
+		sidecars := NewSidecars()
+		response := &ybApi.ReadResponsePB{}
+		assert.Nil(tt, c.ExecuteContext(context.Background(),
+			&ybApi.ReadRequestPB{TabletId: []byte("tablet")}, response, CallWithSidecars(sidecars)))
+		if assert.Equal(tt, 2, sidecars.Len()) {
+			rows, err := sidecars.Get(int(response.GetPgsqlBatch()[0].GetRowsDataSidecar()))
+			assert.Nil(tt, err)
+			assert.Equal(tt, "rows", string(rows))
+			assert.Equal(tt, [][]byte{[]byte("first"), []byte("rows")}, sidecars.All())
+		}
+		_, err := sidecars.Get(2)
+		assert.Error(tt, err)
+	})
+
+	t.Run("it=decodes responses with sidecars without a holder", func(tt *testing.T) {
+		response := &ybApi.ReadResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ReadRequestPB{TabletId: []byte("tablet")}, response))
+		assert.Equal(tt, int32(1), response.GetPgsqlBatch()[0].GetRowsDataSidecar())
+	})
+
+	t.Run("it=rejects invalid sidecar offsets", func(tt *testing.T) {
+		err := c.ExecuteContext(context.Background(),
+			&ybApi.ReadRequestPB{TabletId: []byte("invalid")}, &ybApi.ReadResponsePB{}, CallWithSidecars(NewSidecars()))
+		assert.IsType(tt, &clientErrors.UnprocessableResponseError{}, err)
+	})
+
+	t.Run("it=replaces the sidecars on every call", func(tt *testing.T) {
+		sidecars := NewSidecars()
+		response := &ybApi.ReadResponsePB{}
+		assert.Nil(tt, c.ExecuteContext(context.Background(),
+			&ybApi.ReadRequestPB{TabletId: []byte("retry")}, response, CallWithSidecars(sidecars)))
+		assert.NotNil(tt, response.Error)
+		assert.Equal(tt, 1, sidecars.Len())
+		assert.Nil(tt, c.ExecuteContext(context.Background(),
+			&ybApi.ReadRequestPB{TabletId: []byte("retry")}, response, CallWithSidecars(sidecars)))
+		assert.Equal(tt, 0, sidecars.Len())
+	})
+
+}
+
+func TestSplitSidecars(t *testing.T) {
+
+	t.Run("it=returns the payload without offsets", func(tt *testing.T) {
+		message, sidecars, err := splitSidecars([]byte("message"), nil)
+		assert.Nil(tt, err)
+		assert.Equal(tt, "message", string(message))
+		assert.Empty(tt, sidecars)
+	})
+
+	t.Run("it=slices sidecars by offsets", func(tt *testing.T) {
+		message, sidecars, err := splitSidecars([]byte("messageabcd"), []uint32{7, 8, 8})
+		assert.Nil(tt, err)
+		assert.Equal(tt, "message", string(message))
+		assert.Equal(tt, [][]byte{[]byte("a"), {}, []byte("bcd")}, sidecars)
+	})
+
+	t.Run("it=rejects offsets out of bounds", func(tt *testing.T) {
+		for _, offsets := range [][]uint32{{12}, {7, 5}, {7, 20}} {
+			_, _, err := splitSidecars([]byte("messageabcd"), offsets)
+			assert.Error(tt, err, "offsets %v", offsets)
+		}
+	})
+
+}
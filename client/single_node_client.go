@@ -2,21 +2,29 @@ package client
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
 	"fmt"
+	"io"
+	"math"
 	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/radekg/yugabyte-db-go-client/configs"
 	"github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
 	"github.com/radekg/yugabyte-db-go-client/metrics"
 	"github.com/radekg/yugabyte-db-go-client/utils"
 	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-var recvChunkSize = 4 * 1024
-
 // YBConnectedClient represents a connected client.
 type YBConnectedClient interface {
 	ClientID() string
@@ -25,25 +33,86 @@ type YBConnectedClient interface {
 	// Execute executes the payload against the service
 	// and populates the response with the response data.
 	Execute(payload, response protoreflect.ProtoMessage) error
+	// ExecuteContext executes the payload against the service
+	// and populates the response with the response data.
+	// The remaining context deadline is sent as the call timeout,
+	// the call returns the context error as soon as the context is done.
+	ExecuteContext(ctx context.Context, payload, response protoreflect.ProtoMessage, opts ...CallOption) error
 	// Retrieves the master registration information or error if the request failed.
 	GetMasterRegistration() (*ybApi.GetMasterRegistrationResponsePB, error)
 	// Returns a channel which closed when the client is connected.
 	OnConnected() <-chan struct{}
 	// Returns a channel which will return an error if connect fails.
 	OnConnectError() <-chan error
+	// Returns the server version detected when the client connected.
+	// The version is detected only if version profiles are configured.
+	ServerVersion() (ServerVersion, bool)
+}
+
+// rpcResponse is a response frame routed to the caller waiting
+// for the call ID from the response header.
+type rpcResponse struct {
+	header *ybApi.ResponseHeader
+	reader *bytes.Buffer
+	// size is the size of the response frame including the length prefix:
+	size int
 }
 
 type defaultSingleNodeClient struct {
-	id              string
-	originalConfig  *configs.YBSingleNodeClientConfig
-	callCounter     int
-	chanConnected   chan struct{}
-	chanConnectErr  chan error
-	closeFunc       func() error
-	conn            net.Conn
+	id             string
+	originalConfig *configs.YBSingleNodeClientConfig
+	callCounter    int32
+	chanConnected  chan struct{}
+	chanConnectErr chan error
+	closeFunc      func() error
+	conn           net.Conn
+	// inFlight holds a slot for every request awaiting a response,
+	// nil if the number of in-flight requests is not limited:
+	inFlight        chan struct{}
+	interceptors    []UnaryInterceptor
 	logger          hclog.Logger
 	metricsCallback metrics.Callback
-	svcRegistry     ServiceRegistry
+	tracer          trace.Tracer
+	versionProfiles VersionProfiles
+
+	// serverVersion is detected when connecting if version profiles are configured,
+	// the service registry is replaced with the registry of the selected profile:
+	serverVersion *ServerVersion
+	svcRegistry   ServiceRegistry
+	versionLock   *sync.Mutex
+
+	// pending calls are keyed by the call ID:
+	pending     map[int32]chan *rpcResponse
+	pendingLock *sync.Mutex
+	// readErr is set once the reader loop stops,
+	// all subsequent calls fail with that error:
+	readErr   error
+	writeLock *sync.Mutex
+}
+
+// newSingleNodeClient returns a new client for the connection.
+// If the service registry is nil, a registry with all service definitions is loaded.
+func newSingleNodeClient(cfg *configs.YBSingleNodeClientConfig, conn net.Conn, svcRegistry ServiceRegistry) *defaultSingleNodeClient {
+	if svcRegistry == nil {
+		svcRegistry = NewLoadedServiceRegistry()
+	}
+	return &defaultSingleNodeClient{
+		id:             fmt.Sprintf("client-%d", time.Now().Unix()),
+		originalConfig: cfg,
+		chanConnected:  make(chan struct{}, 1),
+		chanConnectErr: make(chan error, 1),
+		closeFunc: func() error {
+			return conn.Close()
+		},
+		conn:        conn,
+		inFlight:    newInFlightLimit(cfg.MaxInFlightRequests),
+		svcRegistry: svcRegistry,
+		tracer:      defaultTracer(),
+		pending:     map[int32]chan *rpcResponse{},
+		pendingLock: &sync.Mutex{},
+		versionLock: &sync.Mutex{},
+		writeLock:   &sync.Mutex{},
+	}
 }
 
 // Close closes a connected client.
@@ -59,7 +128,32 @@ func (c *defaultSingleNodeClient) Close() error {
 // Execute executes the payload against the service
 // and populates the response with the response data.
 func (c *defaultSingleNodeClient) Execute(payload, response protoreflect.ProtoMessage) error {
-	return c.executeOp(payload, response)
+	return c.ExecuteContext(context.Background(), payload, response)
+}
+
+// ExecuteContext executes the payload against the service
+// and populates the response with the response data.
+func (c *defaultSingleNodeClient) ExecuteContext(ctx context.Context, payload, response protoreflect.ProtoMessage, opts ...CallOption) error {
+	options := newCallOptions(opts...)
+	svcInfo := resolveServiceInfo(c.serviceRegistry(), payload, options)
+	if svcInfo == nil {
+		c.metricsCallback.ClientError()
+		c.metricsCallback.ClientMessageSendFailure()
+		return &errors.ProtoServiceError{
+			ProtoType: payload.ProtoReflect().Descriptor().FullName(),
+		}
+	}
+	return chainUnaryInterceptors(c.interceptors, svcInfo, func(ctx context.Context, payload, response protoreflect.ProtoMessage) error {
+		ctx, span := startCallSpan(ctx, c.tracer, svcInfo, append(c.peerAttributes(),
+			AttributeAttempt.Int(int(attemptFromContext(ctx))))...)
+		rpcCallback := metrics.AsRPCCallback(c.metricsCallback)
+		rpcCallback.RPCStarted(svcInfo.Service(), svcInfo.Method(), c.originalConfig.MasterHostPort)
+		started := time.Now()
+		err := c.executeOp(ctx, svcInfo, payload, response, options)
+		rpcCallback.RPCFinished(c.rpcEvent(ctx, svcInfo, time.Since(started), response, err))
+		endCallSpan(span, response, err)
+		return err
+	})(ctx, payload, response)
 }
 
 // GetMasterRegistration retrieves the master registration information or error if the request failed.
@@ -82,6 +176,16 @@ func (c *defaultSingleNodeClient) OnConnectError() <-chan error {
 	return c.chanConnectErr
 }
 
+// ServerVersion returns the server version detected when the client connected.
+func (c *defaultSingleNodeClient) ServerVersion() (ServerVersion, bool) {
+	c.versionLock.Lock()
+	defer c.versionLock.Unlock()
+	if c.serverVersion == nil {
+		return ServerVersion{}, false
+	}
+	return *c.serverVersion, true
+}
+
 func (c *defaultSingleNodeClient) WithMetricsCallback(callback metrics.Callback) YBConnectedClient {
 	c.metricsCallback = callback
 	return c
@@ -90,10 +194,9 @@ func (c *defaultSingleNodeClient) WithMetricsCallback(callback metrics.Callback)
 /// Private interface
 
 func (c *defaultSingleNodeClient) afterConnect() *defaultSingleNodeClient {
-	loadServiceDefinitions(c.svcRegistry)
 	go func() {
 		c.logger.Debug("sending connection header")
-		header := append([]byte("YB"), 1)
+		header := wire.ConnectionHeader
 		n, err := c.conn.Write(header)
 		if err != nil {
 			c.chanConnectErr <- &errors.ProtocolConnectionHeaderWriteError{
@@ -112,45 +215,134 @@ func (c *defaultSingleNodeClient) afterConnect() *defaultSingleNodeClient {
 			return
 		}
 		c.logger.Debug("client connected")
+		go c.readLoop()
+		if len(c.versionProfiles) > 0 {
+			c.selectVersionProfile()
+		}
 		close(c.chanConnected)
 	}()
 	return c
 }
 
-func (c *defaultSingleNodeClient) callID() int {
-	currentID := c.callCounter
-	c.callCounter = c.callCounter + 1
-	return currentID
+// selectVersionProfile detects the server version and selects the service registry
+// of the matching version profile. The client keeps its service registry
+// if the version cannot be detected.
+func (c *defaultSingleNodeClient) selectVersionProfile() {
+	version, err := detectServerVersion(context.Background(), c, time.Duration(c.originalConfig.OpTimeout)*time.Millisecond)
+	if err != nil {
+		c.logger.Warn("failed detecting server version", "reason", err)
+		return
+	}
+	c.logger.Debug("detected server version", "version", version.String())
+	c.versionLock.Lock()
+	defer c.versionLock.Unlock()
+	c.serverVersion = &version
+	c.svcRegistry = c.versionProfiles.registry(version, c.svcRegistry)
 }
 
-func (c *defaultSingleNodeClient) recv() (*bytes.Buffer, error) {
-	buf := bytes.NewBuffer([]byte{})
+func (c *defaultSingleNodeClient) serviceRegistry() ServiceRegistry {
+	c.versionLock.Lock()
+	defer c.versionLock.Unlock()
+	return c.svcRegistry
+}
+
+func (c *defaultSingleNodeClient) callID() int32 {
+	return atomic.AddInt32(&c.callCounter, 1) - 1
+}
+
+// readLoop reads response frames for as long as the connection is usable
+// and routes every frame to the caller waiting for its call ID.
+func (c *defaultSingleNodeClient) readLoop() {
 	for {
-		chunk := make([]byte, recvChunkSize)
-		n, err := c.conn.Read(chunk)
+		buffer, err := c.recv()
 		if err != nil {
-			return buf, err
+			c.failPending(err)
+			c.conn.Close()
+			return
 		}
-		c.metricsCallback.ClientBytesReceived(n)
-		// we read an EOF, finished reading
-		// previous iteration was fitting
-		// all in one recvChunkSize
-		if n == 4 && chunk[0] == 0 && chunk[1] == 0 && chunk[2] == 0 && chunk[3] == 0 {
-			break
+		frameSize := buffer.Len() + wire.FrameLengthSize
+		responseHeader, err := c.readResponseHeader(buffer)
+		if err != nil {
+			// we can't tell who's waiting for this frame,
+			// nothing read from this connection can be trusted anymore:
+			c.logger.Error("closing connection after response frame with unprocessable header", "reason", err)
+			c.failPending(&errors.UnprocessableResponseError{Cause: err})
+			c.conn.Close()
+			return
 		}
-		// otherwise, read what we've got:
-		buf.Write(chunk[0:n])
-		// if we have read exactly recvChunkSize, we continue reading
-		if n == recvChunkSize {
+		c.pendingLock.Lock()
+		chanResponse, ok := c.pending[responseHeader.GetCallId()]
+		delete(c.pending, responseHeader.GetCallId())
+		c.pendingLock.Unlock()
+		if !ok {
+			c.logger.Warn("dropping response for unknown call", "call-id", responseHeader.GetCallId())
 			continue
 		}
-		// we can't read more so this implies we read less
-		break
+		chanResponse <- &rpcResponse{
+			header: responseHeader,
+			reader: buffer,
+			size:   frameSize,
+		}
 	}
-	return buf, nil
+}
+
+// registerCall registers a pending call. Returns an error if the reader loop
+// has already stopped and no response will ever be received.
+func (c *defaultSingleNodeClient) registerCall(callID int32) (chan *rpcResponse, error) {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+	chanResponse := make(chan *rpcResponse, 1)
+	c.pending[callID] = chanResponse
+	return chanResponse, nil
+}
+
+func (c *defaultSingleNodeClient) unregisterCall(callID int32) {
+	c.pendingLock.Lock()
+	delete(c.pending, callID)
+	c.pendingLock.Unlock()
+}
+
+// failPending closes all pending calls, they observe
+// the error set on the client. Whatever stopped the reader loop,
+// the connection is closed and the calls require a reconnect.
+func (c *defaultSingleNodeClient) failPending(err error) {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+	c.readErr = &errors.ConnectionClosedError{Cause: err}
+	for callID, chanResponse := range c.pending {
+		close(chanResponse)
+		delete(c.pending, callID)
+	}
+}
+
+func (c *defaultSingleNodeClient) pendingError() error {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+	return c.readErr
+}
+
+func (c *defaultSingleNodeClient) maxFrameSize() uint32 {
+	if c.originalConfig.MaxFrameSize == 0 {
+		return configs.DefaultMaxFrameSize
+	}
+	return c.originalConfig.MaxFrameSize
+}
+
+func (c *defaultSingleNodeClient) recv() (*bytes.Buffer, error) {
+	frame, err := wire.ReadFrame(c.conn, c.maxFrameSize())
+	if err != nil {
+		return nil, err
+	}
+	c.metricsCallback.ClientBytesReceived(len(frame) + wire.FrameLengthSize)
+	return bytes.NewBuffer(frame), nil
 }
 
 func (c *defaultSingleNodeClient) send(buf *bytes.Buffer) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
 	nBytesToWrite := buf.Len()
 	n, err := c.conn.Write(buf.Bytes())
 	if err != nil {
@@ -163,27 +355,15 @@ func (c *defaultSingleNodeClient) send(buf *bytes.Buffer) error {
 	return nil
 }
 
-func (c *defaultSingleNodeClient) readResponseInto(reader *bytes.Buffer, m protoreflect.ProtoMessage) error {
+func (c *defaultSingleNodeClient) readResponseHeader(reader *bytes.Buffer) (*ybApi.ResponseHeader, error) {
 
-	opLogger := c.logger.With("message", m.ProtoReflect().Type().Descriptor().Name())
-
-	// Read the complete data length:
-	// https://github.com/yugabyte/yugabyte-db/blob/v2.7.2/java/yb-client/src/main/java/org/yb/client/CallResponse.java#L71
-	var dataLength int32
-	if err := binary.Read(reader, binary.BigEndian, &dataLength); err != nil {
-		opLogger.Error("failed reading response data length", "reason", err)
-		return &errors.ReceiveError{
-			Cause: fmt.Errorf("response data length read failed: %s", err.Error()),
-		}
-	}
-
-	opLogger.Trace("data-length", "value", dataLength)
+	opLogger := c.logger
 
 	// https://github.com/yugabyte/yugabyte-db/blob/v2.7.2/java/yb-client/src/main/java/org/yb/client/CallResponse.java#L76
 	responseHeaderLength, err := utils.ReadUvarint32(reader)
 	if err != nil {
 		opLogger.Error("failed reading response header length", "reason", err)
-		return &errors.ReceiveError{
+		return nil, &errors.ReceiveError{
 			Cause: fmt.Errorf("response header length read failed: %s", err.Error()),
 		}
 	}
@@ -193,10 +373,10 @@ func (c *defaultSingleNodeClient) readResponseInto(reader *bytes.Buffer, m proto
 	// Now I can read the response header:
 	// https://github.com/yugabyte/yugabyte-db/blob/v2.7.2/java/yb-client/src/main/java/org/yb/client/CallResponse.java#L78
 	responseHeaderBuf := make([]byte, responseHeaderLength)
-	n, err := reader.Read(responseHeaderBuf)
+	n, err := io.ReadFull(reader, responseHeaderBuf)
 	if err != nil {
 		opLogger.Error("failed reading response header", "reason", err)
-		return &errors.ReceiveError{
+		return nil, &errors.ReceiveError{
 			Cause: fmt.Errorf("response header read failed: %s", err.Error()),
 		}
 	}
@@ -211,7 +391,7 @@ func (c *defaultSingleNodeClient) readResponseInto(reader *bytes.Buffer, m proto
 		opLogger.Error("response header read bytes count != expected count",
 			"expected-header-length", responseHeaderLength,
 			"read-header-length", n)
-		return &errors.ReceiveError{
+		return nil, &errors.ReceiveError{
 			Cause: fmt.Errorf("response header incomplete: read %d bytes vs expected %d",
 				n, responseHeaderLength),
 		}
@@ -221,13 +401,19 @@ func (c *defaultSingleNodeClient) readResponseInto(reader *bytes.Buffer, m proto
 	protoErr := utils.DeserializeProto(responseHeaderBuf, responseHeader)
 	if protoErr != nil {
 		opLogger.Error("failed unmarshalling response header", "reason", protoErr)
-		return &errors.ReceiveError{
+		return nil, &errors.ReceiveError{
 			Cause: fmt.Errorf("response header unprocessable: %s", protoErr.Error()),
 		}
 	}
 
-	opLogger = opLogger.With("call-id", *responseHeader.CallId,
-		"is-error", *responseHeader.IsError,
+	return responseHeader, nil
+}
+
+func (c *defaultSingleNodeClient) readResponseInto(responseHeader *ybApi.ResponseHeader, reader *bytes.Buffer, m protoreflect.ProtoMessage, sidecars *Sidecars) error {
+
+	opLogger := c.logger.With("message", m.ProtoReflect().Type().Descriptor().Name(),
+		"call-id", responseHeader.GetCallId(),
+		"is-error", responseHeader.GetIsError(),
 		"sidecars-count", len(responseHeader.SidecarOffsets))
 
 	// This here is currently a guess but I believe the corretc mechanism sits here:
@@ -246,13 +432,16 @@ func (c *defaultSingleNodeClient) readResponseInto(reader *bytes.Buffer, m proto
 
 	// if there was no data but the call did not result in an error,
 	// return successful no data response:
-	if !*responseHeader.IsError && responsePayloadLength == 0 {
+	if !responseHeader.GetIsError() && responsePayloadLength == 0 {
 		opLogger.Debug("payload was empty but no error, assuming OK")
+		if sidecars != nil {
+			sidecars.set([][]byte{})
+		}
 		return nil
 	}
 
 	responsePayloadBuf := make([]byte, responsePayloadLength)
-	n, err = reader.Read(responsePayloadBuf)
+	n, err := io.ReadFull(reader, responsePayloadBuf)
 	if err != nil {
 		opLogger.Error("failed reading response payload", "reason", err)
 		return &errors.ReceiveError{
@@ -264,52 +453,13 @@ func (c *defaultSingleNodeClient) readResponseInto(reader *bytes.Buffer, m proto
 		"expected-payload-length", responsePayloadLength,
 		"read-payload-length", n)
 
-	if uint64(n) < responsePayloadLength {
-		opLogger.Trace("not all data consumed yet, receiving remainder...",
-			"expected-payload-length", responsePayloadLength,
-			"consumed-payload-length", n)
-		for {
-			buf, err := c.recv()
-			if err != nil {
-				opLogger.Error("response payload read error",
-					"expected-payload-length", responsePayloadLength,
-					"read-payload-length", n,
-					"reason", err)
-				return &errors.ReceiveError{
-					Cause: fmt.Errorf("response payload read error: read %d bytes vs expected %d, reason: %s",
-						n, responsePayloadLength, err.Error()),
-				}
-			}
-
-			n = n + buf.Len()
-			responsePayloadBuf = append(responsePayloadBuf, buf.Bytes()...)
-
-			if uint64(n) == responsePayloadLength {
-				opLogger.Trace("consumed all expected data",
-					"expected-payload-length", responsePayloadLength,
-					"consumed-payload-length", n)
-				break
-			}
-
-			if uint64(n) > responsePayloadLength {
-				opLogger.Error("consumed too much data",
-					"expected-payload-length", responsePayloadLength,
-					"consumed-payload-length", n)
-				return &errors.ReceiveError{
-					Cause: fmt.Errorf("response payload too long: read %d bytes vs expected %d",
-						n, responsePayloadLength),
-				}
-			}
-		}
-	}
-
-	if *responseHeader.IsError {
+	if responseHeader.GetIsError() {
 		errorResponse := &ybApi.ErrorStatusPB{}
 		errorUnmarshalErr := utils.DeserializeProto(responsePayloadBuf, errorResponse)
 		if errorUnmarshalErr != nil {
 			return &errors.UnprocessableResponseError{
 				Cause:           errorUnmarshalErr,
-				ConsumedPayload: responseHeaderBuf,
+				ConsumedPayload: responsePayloadBuf,
 			}
 		}
 		return &errors.ServiceRPCError{
@@ -317,32 +467,56 @@ func (c *defaultSingleNodeClient) readResponseInto(reader *bytes.Buffer, m proto
 		}
 	}
 
-	protoErr2 := utils.DeserializeProto(responsePayloadBuf, m)
+	// the payload length includes the sidecars following the response message:
+	messageBuf, sidecarsData, err := splitSidecars(responsePayloadBuf, responseHeader.SidecarOffsets)
+	if err != nil {
+		opLogger.Error("failed reading response sidecars", "reason", err)
+		return &errors.UnprocessableResponseError{
+			Cause:           err,
+			ConsumedPayload: responsePayloadBuf,
+		}
+	}
+	if sidecars != nil {
+		sidecars.set(sidecarsData)
+	}
+
+	protoErr2 := utils.DeserializeProto(messageBuf, m)
 	if protoErr2 != nil {
 		return &errors.UnprocessableResponseError{
 			Cause:           protoErr2,
-			ConsumedPayload: responseHeaderBuf,
+			ConsumedPayload: responsePayloadBuf,
 		}
 	}
 
 	return nil
 }
 
-func (c *defaultSingleNodeClient) executeOp(payload, result protoreflect.ProtoMessage) error {
+func (c *defaultSingleNodeClient) executeOp(ctx context.Context, svcInfo ServiceInfo, payload, result protoreflect.ProtoMessage, options *callOptions) error {
 
-	svcInfo := c.svcRegistry.Get(payload)
-	if svcInfo == nil {
+	// the timeout is computed after waiting for a free slot:
+	release, err := c.acquireInFlight(ctx)
+	if err != nil {
 		c.metricsCallback.ClientError()
 		c.metricsCallback.ClientMessageSendFailure()
-		return &errors.ProtoServiceError{
-			ProtoType: payload.ProtoReflect().Descriptor().FullName(),
-		}
+		return err
 	}
+	defer release()
+
+	timeoutMillis, err := c.timeoutMillis(ctx)
+	if err != nil {
+		c.metricsCallback.ClientError()
+		c.metricsCallback.ClientMessageSendFailure()
+		return err
+	}
+
+	span := trace.SpanFromContext(ctx)
 
+	callID := c.callID()
+	span.SetAttributes(AttributeCallID.Int64(int64(callID)))
 	requestHeader := &ybApi.RequestHeader{
-		CallId:        utils.PInt32(int32(c.callID())),
+		CallId:        utils.PInt32(callID),
 		RemoteMethod:  svcInfo.ToRemoteMethodPB(),
-		TimeoutMillis: utils.PUint32(c.originalConfig.OpTimeout),
+		TimeoutMillis: utils.PUint32(timeoutMillis),
 	}
 
 	b := bytes.NewBuffer([]byte{})
@@ -355,18 +529,37 @@ func (c *defaultSingleNodeClient) executeOp(payload, result protoreflect.ProtoMe
 			Payload: payload,
 		}
 	}
+	chanResponse, err := c.registerCall(callID)
+	if err != nil {
+		c.metricsCallback.ClientError()
+		c.metricsCallback.ClientMessageSendFailure()
+		return &errors.ReceiveError{Cause: err}
+	}
+	span.SetAttributes(AttributeBytesSent.Int(b.Len() + wire.FrameLengthSize))
 	if err := c.send(b); err != nil {
+		c.unregisterCall(callID)
 		c.metricsCallback.ClientError()
 		c.metricsCallback.ClientMessageSendFailure()
 		return &errors.SendError{Cause: err}
 	}
-	buffer, err := c.recv()
-	if err != nil {
+	var response *rpcResponse
+	select {
+	case r, ok := <-chanResponse:
+		if !ok {
+			c.metricsCallback.ClientError()
+			c.metricsCallback.ClientMessageSendFailure()
+			return &errors.ReceiveError{Cause: c.pendingError()}
+		}
+		response = r
+		span.SetAttributes(AttributeBytesReceived.Int(r.size))
+	case <-ctx.Done():
+		// the reader loop drops the response if it arrives later:
+		c.unregisterCall(callID)
 		c.metricsCallback.ClientError()
 		c.metricsCallback.ClientMessageSendFailure()
-		return &errors.ReceiveError{Cause: err}
+		return ctx.Err()
 	}
-	readResponseErr := c.readResponseInto(buffer, result)
+	readResponseErr := c.readResponseInto(response.header, response.reader, result, options.sidecars)
 	if readResponseErr != nil {
 		c.metricsCallback.ClientError()
 		c.metricsCallback.ClientMessageSendFailure()
@@ -376,6 +569,96 @@ func (c *defaultSingleNodeClient) executeOp(payload, result protoreflect.ProtoMe
 	return nil
 }
 
+// newInFlightLimit returns the in-flight requests semaphore for the limit,
+// nil if the requests are not limited.
+func newInFlightLimit(limit int32) chan struct{} {
+	if limit == configs.NoInFlightLimit {
+		return nil
+	}
+	if limit <= 0 {
+		limit = configs.DefaultMaxInFlightRequests
+	}
+	return make(chan struct{}, limit)
+}
+
+// acquireInFlight waits for a free in-flight request slot
+// and returns the function releasing the slot.
+func (c *defaultSingleNodeClient) acquireInFlight(ctx context.Context) (func(), error) {
+	if c.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case c.inFlight <- struct{}{}:
+		return func() { <-c.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// timeoutMillis returns the call timeout sent in the request header.
+// If the context has a deadline, the remaining time is used instead of the operation timeout.
+func (c *defaultSingleNodeClient) timeoutMillis(ctx context.Context) (uint32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return c.originalConfig.OpTimeout, nil
+	}
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+	if remaining > math.MaxUint32 {
+		return math.MaxUint32, nil
+	}
+	return uint32(remaining), nil
+}
+
+func (c *defaultSingleNodeClient) withInterceptors(interceptors []UnaryInterceptor) *defaultSingleNodeClient {
+	c.interceptors = interceptors
+	return c
+}
+
+// rpcEvent returns the metrics event describing the completed call.
+func (c *defaultSingleNodeClient) rpcEvent(ctx context.Context, svcInfo ServiceInfo, duration time.Duration, response protoreflect.ProtoMessage, err error) *metrics.RPCEvent {
+	event := &metrics.RPCEvent{
+		Service:  svcInfo.Service(),
+		Method:   svcInfo.Method(),
+		Host:     c.originalConfig.MasterHostPort,
+		Duration: duration,
+		Outcome:  metrics.RPCOutcomeSuccess,
+	}
+	switch {
+	case err != nil && ctx.Err() != nil:
+		event.Outcome = metrics.RPCOutcomeCanceled
+		event.ErrorClass = ClassifyError(err).String()
+	case err != nil:
+		event.Outcome = metrics.RPCOutcomeError
+		event.ErrorClass = ClassifyError(err).String()
+		event.ErrorCode = rpcErrorCode(err)
+	default:
+		if code := masterErrorCode(response); code != "" {
+			event.Outcome = metrics.RPCOutcomeMasterError
+			event.ErrorCode = code
+		}
+	}
+	return event
+}
+
+// peerAttributes returns the span attributes describing the target host.
+func (c *defaultSingleNodeClient) peerAttributes() []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(c.originalConfig.MasterHostPort)
+	if err != nil {
+		return []attribute.KeyValue{semconv.NetPeerNameKey.String(c.originalConfig.MasterHostPort)}
+	}
+	attrs := []attribute.KeyValue{semconv.NetPeerNameKey.String(host)}
+	if portNumber, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.NetPeerPortKey.Int(portNumber))
+	}
+	return attrs
+}
+
 func (c *defaultSingleNodeClient) withLogger(logger hclog.Logger) *defaultSingleNodeClient {
 	c.logger = logger
 	return c
@@ -385,3 +668,13 @@ func (c *defaultSingleNodeClient) withMetricsCallback(callback metrics.Callback)
 	c.metricsCallback = callback
 	return c
 }
+
+func (c *defaultSingleNodeClient) withTracer(tracer trace.Tracer) *defaultSingleNodeClient {
+	c.tracer = tracer
+	return c
+}
+
+func (c *defaultSingleNodeClient) withVersionProfiles(profiles VersionProfiles) *defaultSingleNodeClient {
+	c.versionProfiles = profiles
+	return c
+}
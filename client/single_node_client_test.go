@@ -0,0 +1,382 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+	"github.com/radekg/yugabyte-db-go-client/metrics"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type testRequest struct {
+	header       *ybApi.RequestHeader
+	payload      []byte
+	respond      func(response protoreflect.ProtoMessage)
+	respondBytes func(payload []byte)
+	respondFrame func(frame []byte)
+	// reset closes the connection with a TCP reset:
+	reset func()
+}
+
+// testDefaultServerVersion is the version reported by the test servers.
+const testDefaultServerVersion = "2.13.0.0"
+
+// testServe serves the YugabyteDB wire protocol on the server side of the connection.
+// GetStatus is answered with the default server version, every other request
+// is handed over to the handler in a separate goroutine,
+// the handler may respond to the request at any time.
+func testServe(t *testing.T, conn net.Conn, handler func(request *testRequest)) {
+	testServeVersion(t, conn, testDefaultServerVersion, handler)
+}
+
+// testServeVersion serves the YugabyteDB wire protocol reporting the version in the server status.
+func testServeVersion(t *testing.T, conn net.Conn, version string, handler func(request *testRequest)) {
+	handler = testRespondStatus(version, handler)
+	connectionHeader := make([]byte, 3)
+	if _, err := io.ReadFull(conn, connectionHeader); err != nil {
+		return
+	}
+	writeLock := &sync.Mutex{}
+	for {
+		frame, err := wire.ReadFrame(conn, configs.DefaultMaxFrameSize)
+		if err != nil {
+			return
+		}
+		reader := bytes.NewBuffer(frame)
+		headerLength, _ := utils.ReadUvarint32(reader)
+		header := &ybApi.RequestHeader{}
+		if err := utils.DeserializeProto(reader.Next(int(headerLength)), header); err != nil {
+			t.Errorf("failed reading request header: '%v'", err)
+			return
+		}
+		payloadLength, _ := utils.ReadUvarint32(reader)
+		go handler(&testRequest{
+			header:  header,
+			payload: reader.Next(int(payloadLength)),
+			respond: func(response protoreflect.ProtoMessage) {
+				testRespond(t, conn, writeLock, header.GetCallId(), response)
+			},
+			respondBytes: func(payload []byte) {
+				testRespondBytes(t, conn, writeLock, header.GetCallId(), payload)
+			},
+			respondFrame: func(frame []byte) {
+				writeLock.Lock()
+				defer writeLock.Unlock()
+				conn.Write(frame)
+			},
+			reset: func() {
+				if tcpConn, ok := conn.(*net.TCPConn); ok {
+					tcpConn.SetLinger(0)
+				}
+				conn.Close()
+			},
+		})
+	}
+}
+
+// testRespondStatus responds to GetStatus with the version,
+// all other requests are handed over to the handler.
+func testRespondStatus(version string, handler func(request *testRequest)) func(request *testRequest) {
+	return func(request *testRequest) {
+		if request.header.GetRemoteMethod().GetMethodName() != "GetStatus" {
+			handler(request)
+			return
+		}
+		request.respond(&ybApi.GetStatusResponsePB{
+			Status: &ybApi.ServerStatusPB{
+				NodeInstance: &ybApi.NodeInstancePB{
+					PermanentUuid: []byte("server"),
+					InstanceSeqno: utils.PInt64(0),
+				},
+				VersionInfo: &ybApi.VersionInfoPB{
+					VersionNumber: utils.PString(version),
+				},
+			},
+		})
+	}
+}
+
+func testRespond(t *testing.T, conn net.Conn, writeLock *sync.Mutex, callID int32, response protoreflect.ProtoMessage) {
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	// write errors are ignored, the connection may be closed
+	// by the test as soon as the client has read the response:
+	utils.WriteMessages(conn, &ybApi.ResponseHeader{
+		CallId:  utils.PInt32(callID),
+		IsError: utils.PBool(false),
+	}, response)
+}
+
+func testRespondBytes(t *testing.T, conn net.Conn, writeLock *sync.Mutex, callID int32, payload []byte) {
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	header, _ := utils.SerializeProto(&ybApi.ResponseHeader{
+		CallId:  utils.PInt32(callID),
+		IsError: utils.PBool(false),
+	})
+	b := bytes.NewBuffer([]byte{})
+	for _, part := range [][]byte{header, payload} {
+		varint := make([]byte, binary.MaxVarintLen32)
+		b.Write(varint[0:binary.PutUvarint(varint, uint64(len(part)))])
+		b.Write(part)
+	}
+	length := make([]byte, wire.FrameLengthSize)
+	binary.BigEndian.PutUint32(length, uint32(b.Len()))
+	conn.Write(append(length, b.Bytes()...))
+}
+
+func testConnectedClient(t *testing.T, conn net.Conn) *defaultSingleNodeClient {
+	c := newSingleNodeClient(&configs.YBSingleNodeClientConfig{
+		MasterHostPort: "pipe",
+		OpTimeout:      1000,
+	}, conn, nil).
+		withLogger(hclog.NewNullLogger()).
+		withMetricsCallback(metrics.Noop()).
+		afterConnect()
+	select {
+	case err := <-c.OnConnectError():
+		t.Fatalf("expected client to connect but received: '%v'", err)
+	case <-c.OnConnected():
+	}
+	return c
+}
+
+func testResponseFrame(t *testing.T, callID int32, payload *ybApi.ListMastersResponsePB) []byte {
+	b := bytes.NewBuffer([]byte{})
+	responseHeader := &ybApi.ResponseHeader{
+		CallId:  utils.PInt32(callID),
+		IsError: utils.PBool(false),
+	}
+	if err := utils.WriteMessages(b, responseHeader, payload); err != nil {
+		t.Fatalf("failed writing response frame: '%v'", err)
+	}
+	return b.Bytes()
+}
+
+func testListMastersResponse(n int) *ybApi.ListMastersResponsePB {
+	response := &ybApi.ListMastersResponsePB{}
+	for i := 0; i < n; i = i + 1 {
+		response.Masters = append(response.Masters, &ybApi.ServerEntryPB{
+			InstanceId: &ybApi.NodeInstancePB{
+				PermanentUuid: bytes.Repeat([]byte{'a'}, 32),
+				InstanceSeqno: utils.PInt64(int64(i)),
+			},
+		})
+	}
+	return response
+}
+
+func TestSingleNodeClientRecv(t *testing.T) {
+
+	t.Run("it=reads large frames delivered in small writes", func(tt *testing.T) {
+		expected := testListMastersResponse(2000)
+		frame := testResponseFrame(tt, 42, expected)
+		server, clientConn := net.Pipe()
+		defer server.Close()
+		defer clientConn.Close()
+		go func() {
+			for i := 0; i < len(frame); i = i + 1000 {
+				end := i + 1000
+				if end > len(frame) {
+					end = len(frame)
+				}
+				server.Write(frame[i:end])
+			}
+		}()
+		c := &defaultSingleNodeClient{
+			originalConfig:  &configs.YBSingleNodeClientConfig{},
+			conn:            clientConn,
+			logger:          hclog.NewNullLogger(),
+			metricsCallback: metrics.Noop(),
+		}
+		buf, err := c.recv()
+		assert.Nil(tt, err)
+		responseHeader, err := c.readResponseHeader(buf)
+		assert.Nil(tt, err)
+		assert.Equal(tt, int32(42), responseHeader.GetCallId())
+		response := &ybApi.ListMastersResponsePB{}
+		assert.Nil(tt, c.readResponseInto(responseHeader, buf, response, nil))
+		assert.Equal(tt, len(expected.Masters), len(response.Masters))
+	})
+
+}
+
+func TestSingleNodeClientMultiplexing(t *testing.T) {
+
+	t.Run("it=routes out of order responses by call id", func(tt *testing.T) {
+		server, clientConn := net.Pipe()
+		defer server.Close()
+
+		nCalls := 50
+		// hold all requests until every call is in flight,
+		// then respond in reverse order:
+		chanRequests := make(chan *testRequest, nCalls)
+		go testServe(tt, server, func(request *testRequest) {
+			chanRequests <- request
+		})
+
+		c := testConnectedClient(tt, clientConn)
+		defer c.Close()
+
+		go func() {
+			requests := []*testRequest{}
+			for i := 0; i < nCalls; i = i + 1 {
+				requests = append(requests, <-chanRequests)
+			}
+			for i := len(requests) - 1; i >= 0; i = i - 1 {
+				// echo the name filter in the response so the caller can verify routing:
+				request := &ybApi.ListTablesRequestPB{}
+				utils.DeserializeProto(requests[i].payload, request)
+				requests[i].respond(&ybApi.ListTablesResponsePB{
+					Tables: []*ybApi.ListTablesResponsePB_TableInfo{
+						{Id: []byte("id"), Name: request.NameFilter},
+					},
+				})
+			}
+		}()
+
+		wg := &sync.WaitGroup{}
+		for i := 0; i < nCalls; i = i + 1 {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				response := &ybApi.ListTablesResponsePB{}
+				assert.Nil(tt, c.Execute(&ybApi.ListTablesRequestPB{NameFilter: utils.PString(name)}, response))
+				if assert.Equal(tt, 1, len(response.Tables)) {
+					assert.Equal(tt, name, response.Tables[0].GetName())
+				}
+			}(fmt.Sprintf("table-%d", i))
+		}
+
+		chanDone := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(chanDone)
+		}()
+		select {
+		case <-chanDone:
+		case <-time.After(time.Second * 5):
+			tt.Fatal("expected all calls to complete")
+		}
+	})
+
+	t.Run("it=fails in flight calls when the connection breaks", func(tt *testing.T) {
+		server, clientConn := net.Pipe()
+		go testServe(tt, server, func(request *testRequest) {
+			server.Close()
+		})
+		c := testConnectedClient(tt, clientConn)
+		defer c.Close()
+		err := c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.NotNil(tt, err)
+		// subsequent calls fail right away:
+		err = c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.NotNil(tt, err)
+	})
+
+	t.Run("it=fails in flight calls on an unprocessable response header", func(tt *testing.T) {
+		server, clientConn := net.Pipe()
+		defer server.Close()
+		go testServe(tt, server, func(request *testRequest) {
+			// header length claims more bytes than the frame carries:
+			request.respondFrame([]byte{0, 0, 0, 2, 10, 1})
+		})
+		c := testConnectedClient(tt, clientConn)
+		defer c.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		err := c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		receiveErr, ok := err.(*clientErrors.ReceiveError)
+		if assert.True(tt, ok, "expected *ReceiveError but received: '%v'", err) {
+			assert.True(tt, receiveErr.RequiresReconnect())
+		}
+		assert.Equal(tt, ErrorClassRequiresReconnect, ClassifyError(err))
+		// subsequent calls fail right away:
+		err = c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.NotNil(tt, err)
+	})
+
+	t.Run("it=generates unique call ids concurrently", func(tt *testing.T) {
+		c := newSingleNodeClient(&configs.YBSingleNodeClientConfig{}, nil, nil)
+		lock := &sync.Mutex{}
+		seen := map[int32]struct{}{}
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 100; i = i + 1 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id := c.callID()
+				lock.Lock()
+				seen[id] = struct{}{}
+				lock.Unlock()
+			}()
+		}
+		wg.Wait()
+		assert.Equal(tt, 100, len(seen))
+	})
+
+}
+
+func TestSingleNodeClientContext(t *testing.T) {
+
+	t.Run("it=interrupts a blocked read when the context is done", func(tt *testing.T) {
+		server, clientConn := net.Pipe()
+		defer server.Close()
+		go testServe(tt, server, func(request *testRequest) {})
+		c := testConnectedClient(tt, clientConn)
+		defer c.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer cancel()
+		start := time.Now()
+		err := c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.Equal(tt, context.DeadlineExceeded, err)
+		assert.Less(tt, int64(time.Since(start)), int64(time.Second))
+	})
+
+	t.Run("it=sends the remaining deadline as the call timeout", func(tt *testing.T) {
+		server, clientConn := net.Pipe()
+		defer server.Close()
+		chanTimeouts := make(chan uint32, 2)
+		go testServe(tt, server, func(request *testRequest) {
+			chanTimeouts <- request.header.GetTimeoutMillis()
+			request.respond(&ybApi.ListMastersResponsePB{})
+		})
+		c := testConnectedClient(tt, clientConn)
+		defer c.Close()
+
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Equal(tt, uint32(1000), <-chanTimeouts)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+		defer cancel()
+		assert.Nil(tt, c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		timeout := <-chanTimeouts
+		assert.Greater(tt, timeout, uint32(29000))
+		assert.LessOrEqual(tt, timeout, uint32(30000))
+	})
+
+	t.Run("it=does not send when the context is already done", func(tt *testing.T) {
+		c := newSingleNodeClient(&configs.YBSingleNodeClientConfig{}, nil, nil).
+			withLogger(hclog.NewNullLogger()).
+			withMetricsCallback(metrics.Noop())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := c.ExecuteContext(ctx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		assert.Equal(tt, context.Canceled, err)
+	})
+
+}
@@ -1,40 +1,73 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
-	"fmt"
 	"net"
-	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/radekg/yugabyte-db-go-client/configs"
 	"github.com/radekg/yugabyte-db-go-client/metrics"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Connector connects a single node client.
 type Connector interface {
 	Connect(cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error)
+	// ConnectContext connects the client, the context bounds the dial and the TLS handshake.
+	ConnectContext(ctx context.Context, cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error)
+	// Allows configuring the dialer used to establish the connection.
+	// TLS connections are established over the connection returned by the dialer.
+	WithDialer(dialer Dialer) Connector
 	// Allows configuring the logger used by the client.
 	// Uses go-hclog. Users can provide integrate with any logging
 	// framework using https://pkg.go.dev/github.com/hashicorp/go-hclog#InterceptLogger.
 	WithLogger(logger hclog.Logger) Connector
+	// Allows adding interceptors wrapping every call executed by the resulting client.
+	// Interceptors are called in the order they were added.
+	WithInterceptors(interceptors ...UnaryInterceptor) Connector
 	// Allows providing custom implementation of the metrics callback.
 	WithMetricsCallback(callback metrics.Callback) Connector
+	// Allows sharing the service registry between clients.
+	// Without a service registry, every client loads its own registry.
+	WithServiceRegistry(registry ServiceRegistry) Connector
+	// Allows configuring the tracer provider used to trace every call
+	// executed by the resulting client.
+	WithTracerProvider(provider trace.TracerProvider) Connector
+	// Allows configuring the version profiles. The resulting client detects
+	// the server version when connecting and uses the service registry
+	// of the matching profile.
+	WithVersionProfiles(profiles ...VersionProfile) Connector
 }
 
 type defaultClientConnector struct {
+	dialer          Dialer
+	interceptors    []UnaryInterceptor
 	logger          hclog.Logger
 	metricsCallback metrics.Callback
+	svcRegistry     ServiceRegistry
+	tracer          trace.Tracer
+	versionProfiles VersionProfiles
 }
 
 // NewDefaultConnector returns a new instance of the default connector.
 func NewDefaultConnector() Connector {
 	return &defaultClientConnector{
+		dialer:          NewTCPDialer(configs.DefaultDialTimeout, configs.DefaultKeepAlive),
 		logger:          hclog.Default(),
 		metricsCallback: metrics.Noop(),
+		tracer:          defaultTracer(),
 	}
 }
 
+// WithDialer configures the dialer for the connector.
+func (dcc *defaultClientConnector) WithDialer(dialer Dialer) Connector {
+	if dialer != nil {
+		dcc.dialer = dialer
+	}
+	return dcc
+}
+
 // WithLogger configures the logger for the connector and resulting client.
 func (dcc *defaultClientConnector) WithLogger(logger hclog.Logger) Connector {
 	if logger != nil {
@@ -43,6 +76,12 @@ func (dcc *defaultClientConnector) WithLogger(logger hclog.Logger) Connector {
 	return dcc
 }
 
+// WithInterceptors adds interceptors to the resulting client.
+func (dcc *defaultClientConnector) WithInterceptors(interceptors ...UnaryInterceptor) Connector {
+	dcc.interceptors = append(dcc.interceptors, interceptors...)
+	return dcc
+}
+
 // WithMetricsCallback configures the metrics callback for the connector and resulting client.
 func (dcc *defaultClientConnector) WithMetricsCallback(callback metrics.Callback) Connector {
 	if callback != nil {
@@ -51,56 +90,107 @@ func (dcc *defaultClientConnector) WithMetricsCallback(callback metrics.Callback
 	return dcc
 }
 
-// Connect connects to the master server without TLS.
+// WithServiceRegistry configures the service registry used by the resulting client.
+func (dcc *defaultClientConnector) WithServiceRegistry(registry ServiceRegistry) Connector {
+	if registry != nil {
+		dcc.svcRegistry = registry
+	}
+	return dcc
+}
+
+// WithTracerProvider configures the tracer provider for the resulting client.
+func (dcc *defaultClientConnector) WithTracerProvider(provider trace.TracerProvider) Connector {
+	if provider != nil {
+		dcc.tracer = newTracer(provider)
+	}
+	return dcc
+}
+
+// WithVersionProfiles configures the version profiles for the resulting client.
+func (dcc *defaultClientConnector) WithVersionProfiles(profiles ...VersionProfile) Connector {
+	dcc.versionProfiles = append(dcc.versionProfiles, profiles...)
+	return dcc
+}
+
+// Connect connects to the server.
 func (dcc *defaultClientConnector) Connect(cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error) {
+	return dcc.ConnectContext(context.Background(), cfg)
+}
+
+// ConnectContext connects to the server, the context bounds the dial and the TLS handshake.
+func (dcc *defaultClientConnector) ConnectContext(ctx context.Context, cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error) {
 	if cfg.TLSConfig != nil {
-		return dcc.connectTLS(cfg)
+		return dcc.connectTLS(ctx, cfg)
 	}
-	return dcc.connect(cfg)
+	return dcc.connect(ctx, cfg)
 }
 
-func (dcc *defaultClientConnector) connect(cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error) {
+func (dcc *defaultClientConnector) connect(ctx context.Context, cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error) {
 	dcc.logger.Debug("connecting non-TLS client")
-	conn, err := net.Dial("tcp", cfg.MasterHostPort)
+	conn, err := dcc.dialer.DialContext(ctx, "tcp", cfg.MasterHostPort)
 	if err != nil {
 		return nil, err
 	}
-	client := &defaultSingleNodeClient{
-		id:             fmt.Sprintf("client-%d", time.Now().Unix()),
-		originalConfig: cfg,
-		chanConnected:  make(chan struct{}, 1),
-		chanConnectErr: make(chan error, 1),
-		closeFunc: func() error {
-			return conn.Close()
-		},
-		conn:        conn,
-		logger:      dcc.logger,
-		svcRegistry: NewDefaultServiceRegistry(),
-	}
-	return client.
-		withLogger(dcc.logger).
-		withMetricsCallback(dcc.metricsCallback).
-		afterConnect(), nil
+	return dcc.newClient(cfg, conn), nil
 }
 
-func (dcc *defaultClientConnector) connectTLS(cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error) {
+func (dcc *defaultClientConnector) connectTLS(ctx context.Context, cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error) {
 	dcc.logger.Debug("connecting TLS client")
-	conn, err := tls.Dial("tcp", cfg.MasterHostPort, cfg.TLSConfig)
+	conn, err := dcc.dialer.DialContext(ctx, "tcp", cfg.MasterHostPort)
 	if err != nil {
 		return nil, err
 	}
-	client := &defaultSingleNodeClient{
-		originalConfig: cfg,
-		chanConnected:  make(chan struct{}, 1),
-		chanConnectErr: make(chan error, 1),
-		closeFunc: func() error {
-			return conn.Close()
-		},
-		conn:        conn,
-		svcRegistry: NewDefaultServiceRegistry(),
+	tlsConfig := cfg.TLSConfig
+	if tlsConfig.ServerName == "" {
+		// the dialed connection does not carry the host name,
+		// verify the certificate against the host of the address:
+		tlsConfig = tlsConfig.Clone()
+		if host, _, err := net.SplitHostPort(cfg.MasterHostPort); err == nil {
+			tlsConfig.ServerName = host
+		} else {
+			tlsConfig.ServerName = cfg.MasterHostPort
+		}
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := handshakeContext(ctx, tlsConn); err != nil {
+		conn.Close()
+		return nil, err
 	}
-	return client.
+	return dcc.newClient(cfg, tlsConn), nil
+}
+
+func (dcc *defaultClientConnector) newClient(cfg *configs.YBSingleNodeClientConfig, conn net.Conn) YBConnectedClient {
+	return newSingleNodeClient(cfg, conn, dcc.svcRegistry).
+		withInterceptors(dcc.interceptors).
 		withLogger(dcc.logger).
 		withMetricsCallback(dcc.metricsCallback).
-		afterConnect(), nil
+		withTracer(dcc.tracer).
+		withVersionProfiles(dcc.versionProfiles).
+		afterConnect()
+}
+
+// connectSingleNode connects a single node client and waits until
+// the client is ready to execute calls.
+func connectSingleNode(ctx context.Context, connector Connector, cfg *configs.YBSingleNodeClientConfig) (YBConnectedClient, error) {
+	singleNodeClient, err := connector.ConnectContext(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case err := <-singleNodeClient.OnConnectError():
+		singleNodeClient.Close()
+		return nil, err
+	case <-singleNodeClient.OnConnected():
+		// the connected channel is closed also when the connect fails:
+		select {
+		case err := <-singleNodeClient.OnConnectError():
+			singleNodeClient.Close()
+			return nil, err
+		default:
+		}
+		return singleNodeClient, nil
+	case <-ctx.Done():
+		singleNodeClient.Close()
+		return nil, ctx.Err()
+	}
 }
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// ConnectionState is the state of the client connection to the master leader.
+type ConnectionState int
+
+const (
+	// StateIdle is the state of a client which has not been connected yet.
+	StateIdle ConnectionState = iota
+	// StateConnecting is the state of a client looking for the master leader.
+	StateConnecting
+	// StateReady is the state of a client connected to the master leader.
+	StateReady
+	// StateReconnecting is the state of a client which lost the connection to the leader.
+	// The client stays reconnecting when the reconnect fails, the next call reconnects again.
+	StateReconnecting
+	// StateShuttingDown is the state of a client waiting for the calls in progress
+	// before closing. New calls are rejected.
+	StateShuttingDown
+	// StateClosed is the state of a closed client.
+	StateClosed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateReady:
+		return "ready"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateShuttingDown:
+		return "shutting-down"
+	case StateClosed:
+		return "closed"
+	default:
+		return "idle"
+	}
+}
+
+// stateWatchBufferSize is the number of state changes buffered for a watcher.
+const stateWatchBufferSize = 16
+
+// connectionHooks are the lifecycle hooks registered on the client.
+type connectionHooks struct {
+	onLeaderChange []func(oldLeader, newLeader string)
+	onReconnect    []func(leaderAddress string, err error)
+	onClose        []func()
+}
+
+// stateWatchers delivers state changes to the state watchers.
+type stateWatchers struct {
+	lock     *sync.Mutex
+	watchers map[chan ConnectionState]struct{}
+}
+
+func newStateWatchers() *stateWatchers {
+	return &stateWatchers{
+		lock:     &sync.Mutex{},
+		watchers: map[chan ConnectionState]struct{}{},
+	}
+}
+
+// watch returns a channel receiving the current state followed by every state change.
+// The channel is closed when the context is done.
+func (w *stateWatchers) watch(ctx context.Context, current ConnectionState) <-chan ConnectionState {
+	chanState := make(chan ConnectionState, stateWatchBufferSize)
+	chanState <- current
+	w.lock.Lock()
+	w.watchers[chanState] = struct{}{}
+	w.lock.Unlock()
+	go func() {
+		<-ctx.Done()
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		delete(w.watchers, chanState)
+		close(chanState)
+	}()
+	return chanState
+}
+
+// notify sends the state to all watchers without blocking.
+// A watcher not keeping up loses the oldest buffered state,
+// the latest state is always delivered.
+func (w *stateWatchers) notify(state ConnectionState) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for chanState := range w.watchers {
+		for {
+			select {
+			case chanState <- state:
+			default:
+				select {
+				case <-chanState:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// testNextState waits for the next state from the watch channel.
+func testNextState(t *testing.T, chanState <-chan ConnectionState) ConnectionState {
+	select {
+	case state := <-chanState:
+		return state
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected a state change")
+	}
+	return StateIdle
+}
+
+func TestConnectionState(t *testing.T) {
+
+	var followerA int32
+	respond := func(request *testRequest, isLeader bool) {
+		if request.header.GetRemoteMethod().GetMethodName() == "ListMasters" {
+			request.respond(&ybApi.ListMastersResponsePB{})
+			return
+		}
+		if !isLeader {
+			request.respond(&ybApi.ListTablesResponsePB{Error: testNotTheLeader()})
+			return
+		}
+		request.respond(&ybApi.ListTablesResponsePB{})
+	}
+	addressA := testMasterWithRole(t, func() ybApi.PeerRole {
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_FOLLOWER
+		}
+		return ybApi.PeerRole_LEADER
+	}, func(request *testRequest) {
+		respond(request, atomic.LoadInt32(&followerA) == 0)
+	})
+	addressB := testMasterWithRole(t, func() ybApi.PeerRole {
+		if atomic.LoadInt32(&followerA) == 1 {
+			return ybApi.PeerRole_LEADER
+		}
+		return ybApi.PeerRole_FOLLOWER
+	}, func(request *testRequest) {
+		respond(request, atomic.LoadInt32(&followerA) == 1)
+	})
+
+	lock := &sync.Mutex{}
+	leaderChanges := [][]string{}
+	reconnects := []string{}
+	var closes int32
+
+	c := NewYBClient(&configs.YBClientConfig{
+		MasterHostPort: []string{addressA, addressB},
+		OpTimeout:      time.Second,
+	}).WithLogger(hclog.NewNullLogger()).
+		OnLeaderChange(func(oldLeader, newLeader string) {
+			lock.Lock()
+			defer lock.Unlock()
+			leaderChanges = append(leaderChanges, []string{oldLeader, newLeader})
+		}).
+		OnReconnect(func(leaderAddress string, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			assert.Nil(t, err)
+			reconnects = append(reconnects, leaderAddress)
+		}).
+		OnClose(func() {
+			atomic.AddInt32(&closes, 1)
+		})
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	chanState := c.WatchState(ctx)
+
+	t.Run("it=starts idle", func(tt *testing.T) {
+		assert.Equal(tt, StateIdle, c.State())
+		assert.Equal(tt, StateIdle, testNextState(tt, chanState))
+	})
+
+	t.Run("it=becomes ready when connected", func(tt *testing.T) {
+		if err := c.Connect(); err != nil {
+			tt.Fatalf("expected client to connect but received: '%v'", err)
+		}
+		assert.Equal(tt, StateConnecting, testNextState(tt, chanState))
+		assert.Equal(tt, StateReady, testNextState(tt, chanState))
+		assert.Equal(tt, StateReady, c.State())
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Equal(tt, [][]string{{"", addressA}}, leaderChanges)
+	})
+
+	t.Run("it=reconnects to the new leader", func(tt *testing.T) {
+		atomic.StoreInt32(&followerA, 1)
+		response := &ybApi.ListTablesResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListTablesRequestPB{}, response))
+		assert.Nil(tt, response.Error)
+		assert.Equal(tt, StateReconnecting, testNextState(tt, chanState))
+		assert.Equal(tt, StateReady, testNextState(tt, chanState))
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Equal(tt, [][]string{{"", addressA}, {addressA, addressB}}, leaderChanges)
+		assert.Equal(tt, []string{addressB}, reconnects)
+	})
+
+	t.Run("it=calls close hooks", func(tt *testing.T) {
+		assert.Nil(tt, c.Close())
+		assert.Equal(tt, StateShuttingDown, testNextState(tt, chanState))
+		assert.Equal(tt, StateClosed, testNextState(tt, chanState))
+		assert.Equal(tt, StateClosed, c.State())
+		assert.Equal(tt, int32(1), atomic.LoadInt32(&closes))
+		assert.Error(tt, c.Execute(&ybApi.ListTablesRequestPB{}, &ybApi.ListTablesResponsePB{}))
+	})
+
+	t.Run("it=closes the watch channel when the context is done", func(tt *testing.T) {
+		cancelFunc()
+		select {
+		case _, ok := <-chanState:
+			assert.False(tt, ok)
+		case <-time.After(time.Second * 5):
+			tt.Fatal("expected the watch channel to be closed")
+		}
+	})
+
+}
+
+func TestStateWatchers(t *testing.T) {
+
+	t.Run("it=delivers the latest state to slow watchers", func(tt *testing.T) {
+		watchers := newStateWatchers()
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		defer cancelFunc()
+		chanState := watchers.watch(ctx, StateIdle)
+		for i := 0; i < stateWatchBufferSize*2; i = i + 1 {
+			watchers.notify(StateReconnecting)
+		}
+		watchers.notify(StateClosed)
+		var last ConnectionState
+		for i := 0; i < stateWatchBufferSize; i = i + 1 {
+			last = <-chanState
+		}
+		assert.Equal(tt, StateClosed, last)
+	})
+
+}
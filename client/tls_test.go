@@ -0,0 +1,128 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/testutils/common"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// testTLSMaster starts a leader master serving the YugabyteDB wire protocol over TLS.
+// Client certificate common names are sent over the channel, if the client sent one.
+func testTLSMaster(t *testing.T, serverConfig *tls.Config, chanClientNames chan<- string) string {
+	leader := func() ybApi.PeerRole {
+		return ybApi.PeerRole_LEADER
+	}
+	return testListen(t, func(conn net.Conn) {
+		tlsConn := tls.Server(conn, serverConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 && chanClientNames != nil {
+			chanClientNames <- certs[0].Subject.CommonName
+		}
+		testServe(t, tlsConn, func(request *testRequest) {
+			if !testRespondMasterRegistration(request, "tls-master", leader) {
+				request.respond(&ybApi.ListMastersResponsePB{})
+			}
+		})
+	})
+}
+
+func TestClientTLS(t *testing.T) {
+
+	ca := common.NewTestCertificateAuthority(t)
+	caPath := ca.WriteCert(t, t.TempDir())
+
+	t.Run("it=connects with server authentication only", func(tt *testing.T) {
+		address := testTLSMaster(tt, &tls.Config{
+			Certificates: []tls.Certificate{ca.IssueTLS(tt, "master", "127.0.0.1")},
+		}, nil)
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort:    []string{address},
+			OpTimeout:         time.Second,
+			TLSCaCertFilePath: caPath,
+		})
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+	})
+
+	t.Run("it=verifies the server certificate against the server name override", func(tt *testing.T) {
+		address := testTLSMaster(tt, &tls.Config{
+			Certificates: []tls.Certificate{ca.IssueTLS(tt, "master", "yb-master-0.yb-masters.internal")},
+		}, nil)
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort:    []string{address},
+			OpTimeout:         time.Second,
+			TLSCaCertFilePath: caPath,
+			TLSServerName:     "yb-master-0.yb-masters.internal",
+		})
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+
+		// without the override, the certificate does not match the address:
+		err := NewYBClient(&configs.YBClientConfig{
+			MasterHostPort:    []string{address},
+			OpTimeout:         time.Second,
+			TLSCaCertFilePath: caPath,
+		}).WithLogger(hclog.NewNullLogger()).Connect()
+		assert.NotNil(tt, err)
+	})
+
+	t.Run("it=skips verification when requested", func(tt *testing.T) {
+		otherCA := common.NewTestCertificateAuthority(tt)
+		address := testTLSMaster(tt, &tls.Config{
+			Certificates: []tls.Certificate{otherCA.IssueTLS(tt, "master", "127.0.0.1")},
+		}, nil)
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{address},
+			OpTimeout:      time.Second,
+			TLS:            true,
+			TLSSkipVerify:  true,
+		})
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+	})
+
+	t.Run("it=presents the rotated client certificate on new connections", func(tt *testing.T) {
+		clientCAs := x509.NewCertPool()
+		clientCAs.AppendCertsFromPEM(ca.CertPEM())
+		chanClientNames := make(chan string, 10)
+		address := testTLSMaster(tt, &tls.Config{
+			Certificates: []tls.Certificate{ca.IssueTLS(tt, "master", "127.0.0.1")},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		}, chanClientNames)
+
+		dir := tt.TempDir()
+		certPath, keyPath := ca.IssueFiles(tt, dir, "client-1")
+		config := &configs.YBClientConfig{
+			MasterHostPort:        []string{address},
+			OpTimeout:             time.Second,
+			TLSCaCertFilePath:     caPath,
+			TLSCertFilePath:       certPath,
+			TLSKeyFilePath:        keyPath,
+			TLSCertReloadInterval: time.Millisecond,
+		}
+		c := testClient(tt, config)
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Equal(tt, "client-1", <-chanClientNames)
+		c.Close()
+
+		ca.IssueFiles(tt, dir, "client-2")
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(certPath, future, future)
+		os.Chtimes(keyPath, future, future)
+		<-time.After(time.Millisecond * 5)
+
+		c = testClient(tt, config)
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Equal(tt, "client-2", <-chanClientNames)
+	})
+
+}
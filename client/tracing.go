@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TracerName is the name of the tracer used by the clients.
+const TracerName = "github.com/radekg/yugabyte-db-go-client"
+
+// Span attributes specific to YugabyteDB calls.
+const (
+	// AttributeAttempt is the attempt number of the call, counted from 1.
+	AttributeAttempt = attribute.Key("yb.rpc.attempt")
+	// AttributeBytesReceived is the size of the response frame.
+	AttributeBytesReceived = attribute.Key("yb.rpc.bytes_received")
+	// AttributeBytesSent is the size of the request frame.
+	AttributeBytesSent = attribute.Key("yb.rpc.bytes_sent")
+	// AttributeCallID is the call ID sent in the request header.
+	AttributeCallID = attribute.Key("yb.rpc.call_id")
+	// AttributeMasterErrorCode is the master error code from the response.
+	AttributeMasterErrorCode = attribute.Key("yb.master.error_code")
+	// AttributeRPCErrorCode is the RPC error code from the error response.
+	AttributeRPCErrorCode = attribute.Key("yb.rpc.error_code")
+)
+
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	return provider.Tracer(TracerName)
+}
+
+func defaultTracer() trace.Tracer {
+	return newTracer(trace.NewNoopTracerProvider())
+}
+
+// startCallSpan starts a span for the call described by the service info.
+func startCallSpan(ctx context.Context, tracer trace.Tracer, info ServiceInfo, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, info.Service()+"/"+info.Method(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append([]attribute.KeyValue{
+			semconv.RPCSystemKey.String("yugabytedb"),
+			semconv.RPCServiceKey.String(info.Service()),
+			semconv.RPCMethodKey.String(info.Method()),
+		}, attrs...)...))
+}
+
+// endCallSpan records the call outcome and ends the span.
+func endCallSpan(span trace.Span, response protoreflect.ProtoMessage, err error) {
+	if code := masterErrorCode(response); code != "" {
+		span.SetAttributes(AttributeMasterErrorCode.String(code))
+		if err == nil {
+			span.SetStatus(codes.Error, code)
+		}
+	}
+	if err != nil {
+		if code := rpcErrorCode(err); code != "" {
+			span.SetAttributes(AttributeRPCErrorCode.String(code))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// masterErrorCode returns the master error code from the response,
+// empty if the response has no master error.
+func masterErrorCode(response protoreflect.ProtoMessage) string {
+	if masterError := masterErrorFromResponse(response); masterError != nil && masterError.Code != nil {
+		return masterError.Code.String()
+	}
+	return ""
+}
+
+// rpcErrorCode returns the RPC error code from the error,
+// empty if the error is not an RPC error.
+func rpcErrorCode(err error) string {
+	var rpcError *clientErrors.ServiceRPCError
+	if errors.As(err, &rpcError) && rpcError.Cause.Code != nil {
+		return rpcError.Cause.Code.String()
+	}
+	return ""
+}
+
+type attemptContextKey struct{}
+
+// contextWithAttempt returns a context carrying the attempt number
+// recorded by the attempt span.
+func contextWithAttempt(ctx context.Context, attempt int32) context.Context {
+	return context.WithValue(ctx, attemptContextKey{}, attempt)
+}
+
+func attemptFromContext(ctx context.Context) int32 {
+	if attempt, ok := ctx.Value(attemptContextKey{}).(int32); ok {
+		return attempt
+	}
+	return 1
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+func testSpanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	result := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes {
+		result[attr.Key] = attr.Value
+	}
+	return result
+}
+
+func TestClientTracing(t *testing.T) {
+
+	var calls int32
+	address := testMaster(t, func(request *testRequest) {
+		switch request.header.GetRemoteMethod().GetMethodName() {
+		case "ListMasters":
+			if atomic.AddInt32(&calls, 1) == 1 {
+				// not a valid protobuf payload:
+				request.respondBytes([]byte{0xff, 0xff, 0xff})
+				return
+			}
+			request.respond(&ybApi.ListMastersResponsePB{})
+		default:
+			request.respond(&ybApi.ListTablesResponsePB{
+				Error: &ybApi.MasterErrorPB{
+					Code: ybApi.MasterErrorPB_UNKNOWN_ERROR.Enum(),
+					Status: &ybApi.AppStatusPB{
+						Code: ybApi.AppStatusPB_ILLEGAL_STATE.Enum(),
+					},
+				},
+			})
+		}
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	c := testClient(t, &configs.YBClientConfig{
+		MasterHostPort: []string{address},
+		OpTimeout:      time.Second,
+	}, func(c YBClient) YBClient {
+		return c.WithRetryPolicy(&testCountingRetryPolicy{lock: &sync.Mutex{}, retries: 2}).
+			WithTracerProvider(provider)
+	})
+
+	t.Run("it=traces the call and every attempt", func(tt *testing.T) {
+		exporter.Reset()
+		parentCtx, parentSpan := provider.Tracer("test").Start(context.Background(), "parent")
+		assert.Nil(tt, c.ExecuteContext(parentCtx, &ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		parentSpan.End()
+
+		spans := exporter.GetSpans()
+		if !assert.Equal(tt, 4, len(spans)) {
+			return
+		}
+		// spans are exported when they end:
+		firstAttempt, secondAttempt, call, parent := spans[0], spans[1], spans[2], spans[3]
+
+		assert.Equal(tt, "yb.master.MasterService/ListMasters", call.Name)
+		assert.Equal(tt, parent.SpanContext.SpanID(), call.Parent.SpanID())
+		assert.Equal(tt, codes.Unset, call.Status.Code)
+		assert.Equal(tt, 1, len(call.Events))
+		assert.Equal(tt, "retry", call.Events[0].Name)
+
+		for idx, attempt := range []tracetest.SpanStub{firstAttempt, secondAttempt} {
+			assert.Equal(tt, "yb.master.MasterService/ListMasters", attempt.Name)
+			assert.Equal(tt, call.SpanContext.SpanID(), attempt.Parent.SpanID())
+			attrs := testSpanAttributes(attempt)
+			assert.Equal(tt, "yb.master.MasterService", attrs[semconv.RPCServiceKey].AsString())
+			assert.Equal(tt, "ListMasters", attrs[semconv.RPCMethodKey].AsString())
+			assert.Equal(tt, "127.0.0.1", attrs[semconv.NetPeerNameKey].AsString())
+			assert.Equal(tt, int64(idx+1), attrs[AttributeAttempt].AsInt64())
+			assert.Greater(tt, attrs[AttributeBytesSent].AsInt64(), int64(0))
+			assert.Greater(tt, attrs[AttributeBytesReceived].AsInt64(), int64(0))
+			_, hasCallID := attrs[AttributeCallID]
+			assert.True(tt, hasCallID)
+		}
+		assert.Equal(tt, codes.Error, firstAttempt.Status.Code)
+		assert.Equal(tt, codes.Unset, secondAttempt.Status.Code)
+	})
+
+	t.Run("it=records the master error code", func(tt *testing.T) {
+		exporter.Reset()
+		response := &ybApi.ListTablesResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListTablesRequestPB{}, response))
+		spans := exporter.GetSpans()
+		if !assert.Equal(tt, 2, len(spans)) {
+			return
+		}
+		for _, span := range spans {
+			assert.Equal(tt, "UNKNOWN_ERROR", testSpanAttributes(span)[AttributeMasterErrorCode].AsString())
+			assert.Equal(tt, codes.Error, span.Status.Code)
+		}
+	})
+
+}
@@ -0,0 +1,425 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/metrics"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+)
+
+// TabletServerInfo describes a tablet server discovered from the master leader.
+type TabletServerInfo struct {
+	UUID string
+	// HostPorts contains the private RPC addresses followed by the broadcast addresses.
+	HostPorts []string
+	Alive     bool
+}
+
+// YBTServerClient executes calls against tablet servers.
+// Tablet servers are discovered with ListTabletServers executed against
+// the master leader. The client keeps one connection per tablet server.
+type YBTServerClient interface {
+	// Close closes all tablet server connections.
+	// Calls executed after Close fail with errors.ErrClosed.
+	// The master client is not closed.
+	Close() error
+	// Execute executes the payload against the tablet server
+	// and populates the response with the response data.
+	// The tablet server is identified by its UUID or host:port.
+	Execute(tserver string, payload, response protoreflect.ProtoMessage) error
+	// ExecuteContext executes the payload against the tablet server
+	// and populates the response with the response data.
+	// The tablet server is identified by its UUID or host:port.
+	ExecuteContext(ctx context.Context, tserver string, payload, response protoreflect.ProtoMessage, opts ...CallOption) error
+	// ExecuteAsync executes the payload against the tablet server in the background
+	// like ExecuteContext and returns immediately. The returned future completes when the call finishes.
+	ExecuteAsync(ctx context.Context, tserver string, payload, response protoreflect.ProtoMessage, opts ...CallOption) *Future
+	// ExecuteJSON executes the method of the service against the tablet server
+	// with the JSON payload and returns the response as JSON.
+	ExecuteJSON(ctx context.Context, tserver, service, method string, jsonPayload []byte, opts ...CallOption) ([]byte, error)
+	// ExecuteMethod executes the payload against the tablet server like ExecuteContext
+	// using the service and method of the service info instead of the ones registered for the payload type.
+	// The payload and the response must be the input and the output types of the method,
+	// errors.MethodTypeMismatchError is returned otherwise.
+	ExecuteMethod(ctx context.Context, tserver string, svcInfo ServiceInfo, payload, response protoreflect.ProtoMessage, opts ...CallOption) error
+	// Refresh refreshes the list of tablet servers from the master leader.
+	Refresh(ctx context.Context) error
+	// TabletServers returns the live tablet servers known after the last refresh.
+	TabletServers() []TabletServerInfo
+	// Allows configuring the dialer used to connect to the servers.
+	// Defaults to a TCP dialer with the configured dial timeout and keepalive.
+	WithDialer(dialer Dialer) YBTServerClient
+	// Allows configuring the logger used by the client.
+	WithLogger(logger hclog.Logger) YBTServerClient
+	// Allows providing custom implementation of the metrics callback.
+	WithMetricsCallback(callback metrics.Callback) YBTServerClient
+	// Allows providing custom retry policy used for all calls.
+	WithRetryPolicy(policy RetryPolicy) YBTServerClient
+	// Allows configuring the service registry, for example a registry
+	// with additional file descriptor sets loaded. The registry may be shared by clients.
+	WithServiceRegistry(registry ServiceRegistry) YBTServerClient
+	// Allows configuring the tracer provider. Every call is traced with a span
+	// parented by the span from the call context, every attempt is traced with a child span.
+	WithTracerProvider(provider trace.TracerProvider) YBTServerClient
+	// Allows configuring the version profiles. The client detects the version
+	// of every tablet server it connects to and uses the service registry of the matching profile.
+	// The profiles are added to the built-in ones and override those of the same minimum version.
+	WithVersionProfiles(profiles ...VersionProfile) YBTServerClient
+}
+
+type defaultYBTServerClient struct {
+	closed          bool
+	config          *configs.YBClientConfig
+	connections     map[string]YBConnectedClient
+	dialer          Dialer
+	lock            *sync.Mutex
+	logger          hclog.Logger
+	masterClient    YBClient
+	metricsCallback metrics.Callback
+	refreshed       bool
+	retryPolicy     RetryPolicy
+	svcRegistry     ServiceRegistry
+	tracerProvider  trace.TracerProvider
+	tservers        []TabletServerInfo
+	versionProfiles VersionProfiles
+}
+
+// NewYBTServerClient constructs a new instance of the tablet server client.
+// The master client is used to discover tablet servers and must be connected
+// before executing any calls.
+func NewYBTServerClient(masterClient YBClient, config *configs.YBClientConfig) YBTServerClient {
+	config = config.WithDefaults()
+	return &defaultYBTServerClient{
+		config:          config,
+		dialer:          NewTCPDialer(config.DialTimeout, config.KeepAlive),
+		connections:     map[string]YBConnectedClient{},
+		lock:            &sync.Mutex{},
+		logger:          hclog.Default(),
+		masterClient:    masterClient,
+		metricsCallback: metrics.Noop(),
+		retryPolicy:     NewExponentialBackoffRetryPolicy(config),
+		svcRegistry:     NewLoadedServiceRegistry(),
+		tracerProvider:  trace.NewNoopTracerProvider(),
+		versionProfiles: DefaultVersionProfiles(),
+	}
+}
+
+func (c *defaultYBTServerClient) WithDialer(dialer Dialer) YBTServerClient {
+	if dialer != nil {
+		c.dialer = dialer
+	}
+	return c
+}
+
+func (c *defaultYBTServerClient) WithLogger(logger hclog.Logger) YBTServerClient {
+	c.logger = logger
+	return c
+}
+
+func (c *defaultYBTServerClient) WithMetricsCallback(callback metrics.Callback) YBTServerClient {
+	c.metricsCallback = callback
+	return c
+}
+
+func (c *defaultYBTServerClient) WithRetryPolicy(policy RetryPolicy) YBTServerClient {
+	if policy != nil {
+		c.retryPolicy = policy
+	}
+	return c
+}
+
+func (c *defaultYBTServerClient) WithServiceRegistry(registry ServiceRegistry) YBTServerClient {
+	if registry != nil {
+		c.svcRegistry = registry
+	}
+	return c
+}
+
+func (c *defaultYBTServerClient) WithTracerProvider(provider trace.TracerProvider) YBTServerClient {
+	if provider != nil {
+		c.tracerProvider = provider
+	}
+	return c
+}
+
+func (c *defaultYBTServerClient) WithVersionProfiles(profiles ...VersionProfile) YBTServerClient {
+	c.versionProfiles = append(c.versionProfiles, profiles...)
+	return c
+}
+
+func (c *defaultYBTServerClient) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	var closeErr error
+	for address, connectedClient := range c.connections {
+		if err := connectedClient.Close(); err != nil {
+			closeErr = err
+		}
+		delete(c.connections, address)
+	}
+	return closeErr
+}
+
+func (c *defaultYBTServerClient) Execute(tserver string, payload, response protoreflect.ProtoMessage) error {
+	return c.ExecuteContext(context.Background(), tserver, payload, response)
+}
+
+func (c *defaultYBTServerClient) ExecuteAsync(ctx context.Context, tserver string, payload, response protoreflect.ProtoMessage, opts ...CallOption) *Future {
+	return newFuture(response, func() error {
+		return c.ExecuteContext(ctx, tserver, payload, response, opts...)
+	})
+}
+
+func (c *defaultYBTServerClient) ExecuteJSON(ctx context.Context, tserver, service, method string, jsonPayload []byte, opts ...CallOption) ([]byte, error) {
+	registry, err := c.serviceRegistry(ctx, tserver)
+	if err != nil {
+		return nil, err
+	}
+	call, err := newDynamicCall(registry, service, method, jsonPayload)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ExecuteContext(ctx, tserver, call.payload, call.response, call.options(opts)...); err != nil {
+		return nil, err
+	}
+	return call.responseJSON()
+}
+
+func (c *defaultYBTServerClient) ExecuteMethod(ctx context.Context, tserver string, svcInfo ServiceInfo, payload, response protoreflect.ProtoMessage, opts ...CallOption) error {
+	if svcInfo != nil {
+		registry, err := c.serviceRegistry(ctx, tserver)
+		if err != nil {
+			return err
+		}
+		if err := checkMethodTypes(registry, svcInfo, payload, response); err != nil {
+			return err
+		}
+	}
+	return c.ExecuteContext(ctx, tserver, payload, response, append(append([]CallOption{}, opts...), callWithServiceInfo(svcInfo))...)
+}
+
+func (c *defaultYBTServerClient) ExecuteContext(ctx context.Context, tserver string, payload, response protoreflect.ProtoMessage, opts ...CallOption) error {
+	if c.isClosed() {
+		return clientErrors.ErrClosed
+	}
+	address, err := c.resolve(ctx, tserver)
+	if err != nil {
+		return err
+	}
+	options := newCallOptions(opts...)
+	svcInfo := resolveServiceInfo(c.svcRegistry, payload, options)
+	if svcInfo == nil {
+		return &clientErrors.ProtoServiceError{
+			ProtoType: payload.ProtoReflect().Descriptor().FullName(),
+		}
+	}
+	retryPolicy := c.retryPolicy
+	if options.retryPolicy != nil {
+		retryPolicy = options.retryPolicy
+	}
+	ctx, span := startCallSpan(ctx, newTracer(c.tracerProvider), svcInfo)
+	err = (&retryingExecutor{
+		logger:          c.logger.With("tserver", address),
+		metricsCallback: c.metricsCallback,
+		currentClient: func() (YBConnectedClient, error) {
+			return c.connection(ctx, address)
+		},
+		reconnect: func(ctx context.Context, failed YBConnectedClient) error {
+			return c.reconnect(ctx, address, failed)
+		},
+	}).execute(ctx, retryPolicy, payload, response, opts...)
+	endCallSpan(span, response, err)
+	return err
+}
+
+func (c *defaultYBTServerClient) Refresh(ctx context.Context) error {
+	request := &ybApi.ListTabletServersRequestPB{}
+	response := &ybApi.ListTabletServersResponsePB{}
+	if err := c.masterClient.ExecuteContext(ctx, request, response); err != nil {
+		return err
+	}
+	if err := clientErrors.NewMasterError(response.Error); err != nil {
+		return err
+	}
+	tservers := []TabletServerInfo{}
+	for _, entry := range response.Servers {
+		info := TabletServerInfo{
+			UUID:  string(entry.GetInstanceId().GetPermanentUuid()),
+			Alive: entry.Alive == nil || entry.GetAlive(),
+		}
+		common := entry.GetRegistration().GetCommon()
+		for _, hostPort := range append(common.GetPrivateRpcAddresses(), common.GetBroadcastAddresses()...) {
+			info.HostPorts = append(info.HostPorts, c.config.TranslateAddress(hostPortPBToString(hostPort)))
+		}
+		tservers = append(tservers, info)
+	}
+	c.lock.Lock()
+	c.tservers = tservers
+	c.refreshed = true
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *defaultYBTServerClient) TabletServers() []TabletServerInfo {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result := []TabletServerInfo{}
+	for _, info := range c.tservers {
+		if info.Alive {
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+func (c *defaultYBTServerClient) isClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+// serviceRegistry returns the service registry of the profile selected
+// for the version of the tablet server. The tablet server is connected
+// to detect its version if version profiles are configured.
+func (c *defaultYBTServerClient) serviceRegistry(ctx context.Context, tserver string) (ServiceRegistry, error) {
+	if len(c.versionProfiles) == 0 {
+		return c.svcRegistry, nil
+	}
+	address, err := c.resolve(ctx, tserver)
+	if err != nil {
+		return nil, err
+	}
+	connectedClient, err := c.connection(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	version, ok := connectedClient.ServerVersion()
+	if !ok {
+		return c.svcRegistry, nil
+	}
+	return c.versionProfiles.registry(version, c.svcRegistry), nil
+}
+
+// resolve returns the address to dial for the target UUID or host:port.
+// Host:port targets are translated and dialed directly, they may be advertised addresses,
+// for example replica addresses from GetTableLocations.
+// The tablet servers list is refreshed if the UUID is unknown.
+func (c *defaultYBTServerClient) resolve(ctx context.Context, target string) (string, error) {
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return c.config.TranslateAddress(target), nil
+	}
+	if address, ok := c.lookup(target); ok {
+		return address, nil
+	}
+	if err := c.Refresh(ctx); err != nil {
+		c.logger.Warn("failed refreshing tablet servers", "reason", err)
+	}
+	if address, ok := c.lookup(target); ok {
+		return address, nil
+	}
+	return "", &clientErrors.TServerNotFoundError{Target: target}
+}
+
+func (c *defaultYBTServerClient) lookup(uuid string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, info := range c.tservers {
+		if info.Alive && len(info.HostPorts) > 0 && info.UUID == uuid {
+			return info.HostPorts[0], true
+		}
+	}
+	return "", false
+}
+
+// connection returns the connection to the address,
+// connects if there is no connection yet.
+func (c *defaultYBTServerClient) connection(ctx context.Context, address string) (YBConnectedClient, error) {
+	c.lock.Lock()
+	closed := c.closed
+	connectedClient, ok := c.connections[address]
+	c.lock.Unlock()
+	if closed {
+		return nil, clientErrors.ErrClosed
+	}
+	if ok {
+		return connectedClient, nil
+	}
+	newClient, err := c.connect(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// the client could have been closed while connecting:
+	if c.closed {
+		newClient.Close()
+		return nil, clientErrors.ErrClosed
+	}
+	// another call could have connected in the meantime:
+	if connectedClient, ok := c.connections[address]; ok {
+		newClient.Close()
+		return connectedClient, nil
+	}
+	c.connections[address] = newClient
+	return newClient, nil
+}
+
+// reconnect replaces the failed connection to the address.
+func (c *defaultYBTServerClient) reconnect(ctx context.Context, address string, failed YBConnectedClient) error {
+	c.lock.Lock()
+	if connectedClient, ok := c.connections[address]; ok {
+		if connectedClient != failed {
+			c.lock.Unlock()
+			return nil
+		}
+		delete(c.connections, address)
+	}
+	c.lock.Unlock()
+	// ignore close error
+	// if the client isn't connected, it does not matter to us
+	failed.Close()
+	_, err := c.connection(ctx, address)
+	return err
+}
+
+func (c *defaultYBTServerClient) connect(ctx context.Context, address string) (YBConnectedClient, error) {
+	tlsConfig, err := c.config.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	connectCtx, cancelFunc := context.WithTimeout(ctx, c.config.OpTimeout)
+	defer cancelFunc()
+	connectedClient, err := connectSingleNode(connectCtx, NewDefaultConnector().
+		WithDialer(c.dialer).
+		WithLogger(c.logger.Named("connected-client")).
+		WithMetricsCallback(c.metricsCallback).
+		WithServiceRegistry(c.svcRegistry).
+		WithTracerProvider(c.tracerProvider).
+		WithVersionProfiles(c.versionProfiles...), &configs.YBSingleNodeClientConfig{
+		MasterHostPort:      address,
+		TLSConfig:           tlsConfig,
+		OpTimeout:           uint32(c.config.OpTimeout.Milliseconds()),
+		MaxFrameSize:        c.config.MaxFrameSize,
+		MaxInFlightRequests: c.config.MaxInFlightRequests,
+	})
+	if err != nil {
+		c.metricsCallback.ClientError()
+		return nil, err
+	}
+	c.metricsCallback.ClientConnect()
+	return connectedClient, nil
+}
+
+func hostPortPBToString(hostPort *ybApi.HostPortPB) string {
+	return net.JoinHostPort(hostPort.GetHost(), strconv.FormatUint(uint64(hostPort.GetPort()), 10))
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTServerEntry(t *testing.T, uuid, address string, alive bool) *ybApi.ListTabletServersResponsePB_Entry {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatalf("invalid address '%s': '%v'", address, err)
+	}
+	portNumber, _ := strconv.ParseUint(port, 10, 32)
+	return &ybApi.ListTabletServersResponsePB_Entry{
+		InstanceId: &ybApi.NodeInstancePB{
+			PermanentUuid: []byte(uuid),
+			InstanceSeqno: utils.PInt64(0),
+		},
+		Registration: &ybApi.TSRegistrationPB{
+			Common: &ybApi.ServerRegistrationPB{
+				PrivateRpcAddresses: []*ybApi.HostPortPB{
+					{Host: utils.PString(host), Port: utils.PUint32(uint32(portNumber))},
+				},
+			},
+		},
+		Alive: utils.PBool(alive),
+	}
+}
+
+func TestTServerClient(t *testing.T) {
+
+	var tserverCalls int32
+	tserverAddress := testServer(t, func(request *testRequest) {
+		// fail the first call to verify retries:
+		if atomic.AddInt32(&tserverCalls, 1) == 1 {
+			request.respondBytes([]byte{0xff, 0xff, 0xff})
+			return
+		}
+		request.respond(&ybApi.IsTabletServerReadyResponsePB{
+			TotalTablets: utils.PInt32(7),
+		})
+	})
+
+	masterAddress := testMaster(t, func(request *testRequest) {
+		request.respond(&ybApi.ListTabletServersResponsePB{
+			Servers: []*ybApi.ListTabletServersResponsePB_Entry{
+				testTServerEntry(t, "tserver-1", tserverAddress, true),
+				testTServerEntry(t, "tserver-2", "127.0.0.1:1", false),
+				testTServerEntry(t, "tserver-3", "yb-tserver-3.internal:9100", true),
+			},
+		})
+	})
+
+	config := &configs.YBClientConfig{
+		MasterHostPort: []string{masterAddress},
+		OpTimeout:      time.Second,
+		RetryInterval:  time.Millisecond,
+		AddressMap: map[string]string{
+			"yb-tserver-3.internal:9100": tserverAddress,
+		},
+	}
+	masterClient := testClient(t, config)
+	tserverClient := NewYBTServerClient(masterClient, config).WithLogger(hclog.NewNullLogger())
+	defer tserverClient.Close()
+
+	t.Run("it=discovers live tablet servers", func(tt *testing.T) {
+		assert.Nil(tt, tserverClient.Refresh(context.Background()))
+		tservers := tserverClient.TabletServers()
+		if assert.Equal(tt, 2, len(tservers)) {
+			assert.Equal(tt, "tserver-1", tservers[0].UUID)
+			assert.Equal(tt, []string{tserverAddress}, tservers[0].HostPorts)
+			// advertised addresses are translated:
+			assert.Equal(tt, "tserver-3", tservers[1].UUID)
+			assert.Equal(tt, []string{tserverAddress}, tservers[1].HostPorts)
+		}
+	})
+
+	t.Run("it=executes against a tablet server by uuid and host", func(tt *testing.T) {
+		for _, target := range []string{"tserver-1", tserverAddress, "tserver-3", "yb-tserver-3.internal:9100"} {
+			response := &ybApi.IsTabletServerReadyResponsePB{}
+			assert.Nil(tt, tserverClient.Execute(target, &ybApi.IsTabletServerReadyRequestPB{}, response))
+			assert.Equal(tt, int32(7), response.GetTotalTablets())
+		}
+	})
+
+	t.Run("it=reports unknown tablet servers", func(tt *testing.T) {
+		err := tserverClient.Execute("tserver-2", &ybApi.IsTabletServerReadyRequestPB{}, &ybApi.IsTabletServerReadyResponsePB{})
+		assert.IsType(tt, &clientErrors.TServerNotFoundError{}, err)
+	})
+
+}
+
+func TestTServerClientResolve(t *testing.T) {
+
+	tserverAddress := testServer(t, func(request *testRequest) {
+		request.respond(&ybApi.IsTabletServerReadyResponsePB{})
+	})
+
+	var listCalls int32
+	masterAddress := testMaster(t, func(request *testRequest) {
+		atomic.AddInt32(&listCalls, 1)
+		request.respond(&ybApi.ListTabletServersResponsePB{})
+	})
+
+	config := &configs.YBClientConfig{
+		MasterHostPort: []string{masterAddress},
+		OpTimeout:      time.Second,
+		RetryInterval:  time.Millisecond,
+	}
+	masterClient := testClient(t, config)
+
+	t.Run("it=dials host and port targets without refreshing", func(tt *testing.T) {
+		tserverClient := NewYBTServerClient(masterClient, config).WithLogger(hclog.NewNullLogger())
+		defer tserverClient.Close()
+		for i := 0; i < 3; i = i + 1 {
+			assert.Nil(tt, tserverClient.Execute(tserverAddress, &ybApi.IsTabletServerReadyRequestPB{}, &ybApi.IsTabletServerReadyResponsePB{}))
+		}
+		assert.Equal(tt, int32(0), atomic.LoadInt32(&listCalls))
+	})
+
+	t.Run("it=reconnects after the tablet server resets the connection", func(tt *testing.T) {
+		var calls int32
+		resettingAddress := testServer(tt, func(request *testRequest) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				request.reset()
+				return
+			}
+			request.respond(&ybApi.IsTabletServerReadyResponsePB{})
+		})
+		tserverClient := NewYBTServerClient(masterClient, config).WithLogger(hclog.NewNullLogger())
+		defer tserverClient.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		assert.Nil(tt, tserverClient.ExecuteContext(ctx, resettingAddress, &ybApi.IsTabletServerReadyRequestPB{}, &ybApi.IsTabletServerReadyResponsePB{}))
+		assert.Equal(tt, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("it=refuses calls after close", func(tt *testing.T) {
+		tserverClient := NewYBTServerClient(masterClient, config).WithLogger(hclog.NewNullLogger())
+		assert.Nil(tt, tserverClient.Execute(tserverAddress, &ybApi.IsTabletServerReadyRequestPB{}, &ybApi.IsTabletServerReadyResponsePB{}))
+		assert.Nil(tt, tserverClient.Close())
+		err := tserverClient.Execute(tserverAddress, &ybApi.IsTabletServerReadyRequestPB{}, &ybApi.IsTabletServerReadyResponsePB{})
+		assert.Equal(tt, clientErrors.ErrClosed, err)
+	})
+
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ServerVersion is the version of a YugabyteDB server, for example 2.13.0.1.
+type ServerVersion struct {
+	Major    int
+	Minor    int
+	Patch    int
+	Revision int
+}
+
+// ParseServerVersion parses the version number reported by the server,
+// for example 2.13.0.1 or 2.11.2.0-b89. Missing components are zero
+// and the build suffix is ignored.
+func ParseServerVersion(version string) (ServerVersion, error) {
+	result := ServerVersion{}
+	number := strings.TrimSpace(version)
+	if index := strings.Index(number, "-"); index >= 0 {
+		number = number[:index]
+	}
+	parts := strings.Split(number, ".")
+	if number == "" || len(parts) > 4 {
+		return result, &clientErrors.InvalidServerVersionError{Version: version}
+	}
+	components := []*int{&result.Major, &result.Minor, &result.Patch, &result.Revision}
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return result, &clientErrors.InvalidServerVersionError{Version: version}
+		}
+		*components[i] = value
+	}
+	return result, nil
+}
+
+// Compare returns -1, 0 or 1 if the version is lower than,
+// equal to or greater than the other version.
+func (v ServerVersion) Compare(other ServerVersion) int {
+	this := []int{v.Major, v.Minor, v.Patch, v.Revision}
+	that := []int{other.Major, other.Minor, other.Patch, other.Revision}
+	for i := range this {
+		if this[i] < that[i] {
+			return -1
+		}
+		if this[i] > that[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major, v.Minor, v.Patch, v.Revision)
+}
+
+// VersionProfile selects the service registry used for the servers
+// of the minimum version and above, up to the minimum version of the next profile.
+// The registry resolves the service names and, for calls executed by name,
+// the message definitions, for example from a file descriptor set
+// generated from the protobuf definitions of the server release.
+// The registry replaces the default one, it must register all services used by the client.
+// A profile without a registry uses the registry configured for the client.
+// The service names map the service names of the registry
+// to the names the servers of the profile serve the methods under.
+type VersionProfile struct {
+	MinVersion   ServerVersion
+	Registry     ServiceRegistry
+	ServiceNames map[string]string
+}
+
+// VersionProfiles is a list of version profiles.
+type VersionProfiles []VersionProfile
+
+// legacyServiceNames are the names of the services renamed in YugabyteDB 2.13.
+// The master services split out of MasterService in 2.11.2 keep
+// the yb.master.MasterService name with the custom_service_name option.
+var legacyServiceNames = map[string]string{
+	"yb.master.MasterBackup": "yb.master.MasterBackupService",
+}
+
+// DefaultVersionProfiles returns the built-in version profiles
+// of the YugabyteDB releases the client can talk to:
+// 2.9 and 2.11.0, 2.11.2 and 2.13 and above.
+// The clients use the built-in profiles unless overridden by the configured ones.
+func DefaultVersionProfiles() VersionProfiles {
+	return VersionProfiles{
+		{MinVersion: ServerVersion{Major: 2, Minor: 9}, ServiceNames: legacyServiceNames},
+		{MinVersion: ServerVersion{Major: 2, Minor: 11, Patch: 2}, ServiceNames: legacyServiceNames},
+		{MinVersion: ServerVersion{Major: 2, Minor: 13}},
+	}
+}
+
+// Select returns the profile with the highest minimum version
+// not greater than the version. Of the profiles with the same
+// minimum version, the one listed last is selected.
+func (p VersionProfiles) Select(version ServerVersion) (VersionProfile, bool) {
+	var result VersionProfile
+	found := false
+	for _, profile := range p {
+		if profile.MinVersion.Compare(version) > 0 {
+			continue
+		}
+		if !found || profile.MinVersion.Compare(result.MinVersion) >= 0 {
+			result = profile
+			found = true
+		}
+	}
+	return result, found
+}
+
+// registry returns the registry of the profile selected for the version,
+// the fallback registry if no profile with a registry matches.
+// The services are renamed as configured by the profile.
+func (p VersionProfiles) registry(version ServerVersion, fallback ServiceRegistry) ServiceRegistry {
+	profile, ok := p.Select(version)
+	if !ok {
+		return fallback
+	}
+	registry := fallback
+	if profile.Registry != nil {
+		registry = profile.Registry
+	}
+	if len(profile.ServiceNames) > 0 {
+		registry = newRenamedServiceRegistry(registry, profile.ServiceNames)
+	}
+	return registry
+}
+
+// renamedServiceRegistry calls the services of the wrapped registry
+// under other names. The methods are resolved under the names of the wrapped registry.
+type renamedServiceRegistry struct {
+	ServiceRegistry
+	// names maps the names of the wrapped registry to the names used by the servers:
+	names    map[string]string
+	original map[string]string
+}
+
+func newRenamedServiceRegistry(registry ServiceRegistry, names map[string]string) ServiceRegistry {
+	original := map[string]string{}
+	for name, renamed := range names {
+		original[renamed] = name
+	}
+	return &renamedServiceRegistry{
+		ServiceRegistry: registry,
+		names:           names,
+		original:        original,
+	}
+}
+
+func (r *renamedServiceRegistry) Get(t protoreflect.ProtoMessage) ServiceInfo {
+	info := r.ServiceRegistry.Get(t)
+	if info == nil {
+		return nil
+	}
+	if renamed, ok := r.names[info.Service()]; ok {
+		return NewServiceInfo(renamed, info.Method())
+	}
+	return info
+}
+
+func (r *renamedServiceRegistry) Register(inputTypeName, methodName, svcName string) {
+	if name, ok := r.original[svcName]; ok {
+		svcName = name
+	}
+	r.ServiceRegistry.Register(inputTypeName, methodName, svcName)
+}
+
+func (r *renamedServiceRegistry) Candidates(t protoreflect.ProtoMessage) []MethodInfo {
+	return r.rename(r.ServiceRegistry.Candidates(t))
+}
+
+func (r *renamedServiceRegistry) Duplicates() map[protoreflect.FullName][]MethodInfo {
+	result := map[protoreflect.FullName][]MethodInfo{}
+	for inputType, candidates := range r.ServiceRegistry.Duplicates() {
+		result[inputType] = r.rename(candidates)
+	}
+	return result
+}
+
+func (r *renamedServiceRegistry) LookupMethod(service, method string) (protoreflect.MethodDescriptor, bool) {
+	if name, ok := r.original[service]; ok {
+		service = name
+	}
+	return r.ServiceRegistry.LookupMethod(service, method)
+}
+
+func (r *renamedServiceRegistry) Methods() []MethodInfo {
+	result := r.rename(r.ServiceRegistry.Methods())
+	sortMethodInfos(result)
+	return result
+}
+
+func (r *renamedServiceRegistry) rename(infos []MethodInfo) []MethodInfo {
+	result := []MethodInfo{}
+	for _, info := range infos {
+		renamed, ok := r.names[info.Service()]
+		if !ok {
+			result = append(result, info)
+			continue
+		}
+		result = append(result, &defaultMethodInfo{
+			defaultServiceInfo: defaultServiceInfo{
+				method:  info.Method(),
+				service: renamed,
+			},
+			inputType:  info.InputType(),
+			outputType: info.OutputType(),
+		})
+	}
+	return result
+}
+
+// detectServerVersion queries the server status for the server version.
+func detectServerVersion(ctx context.Context, connectedClient YBConnectedClient, timeout time.Duration) (ServerVersion, error) {
+	if timeout > 0 {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, timeout)
+		defer cancelFunc()
+	}
+	response := &ybApi.GetStatusResponsePB{}
+	if err := connectedClient.ExecuteContext(ctx, &ybApi.GetStatusRequestPB{}, response); err != nil {
+		return ServerVersion{}, err
+	}
+	return ParseServerVersion(response.GetStatus().GetVersionInfo().GetVersionNumber())
+}
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// testVersionedMaster starts a master reporting the version in the server status.
+// The remote methods of all other requests are sent to the channel.
+func testVersionedMaster(t *testing.T, version string, chanMethods chan *ybApi.RemoteMethodPB) string {
+	var address string
+	address = testListen(t, func(conn net.Conn) {
+		testServeVersion(t, conn, version, func(request *testRequest) {
+			if testRespondMasterRegistration(request, address, func() ybApi.PeerRole {
+				return ybApi.PeerRole_LEADER
+			}) {
+				return
+			}
+			chanMethods <- request.header.GetRemoteMethod()
+			request.respond(&ybApi.ListMastersResponsePB{})
+		})
+	})
+	return address
+}
+
+func TestServerVersion(t *testing.T) {
+
+	t.Run("it=parses server versions", func(tt *testing.T) {
+		for input, expected := range map[string]ServerVersion{
+			"2.13.0.1":     {Major: 2, Minor: 13, Revision: 1},
+			"2.11.2.0-b89": {Major: 2, Minor: 11, Patch: 2},
+			"2.9":          {Major: 2, Minor: 9},
+		} {
+			version, err := ParseServerVersion(input)
+			if assert.Nil(tt, err, "version %s", input) {
+				assert.Equal(tt, expected, version)
+			}
+		}
+		for _, input := range []string{"", "2.x", "2.13.0.1.0", "-b89"} {
+			_, err := ParseServerVersion(input)
+			assert.IsType(tt, &clientErrors.InvalidServerVersionError{}, err, "version %q", input)
+		}
+	})
+
+	t.Run("it=compares server versions", func(tt *testing.T) {
+		assert.Equal(tt, -1, ServerVersion{Major: 2, Minor: 9}.Compare(ServerVersion{Major: 2, Minor: 11}))
+		assert.Equal(tt, 1, ServerVersion{Major: 2, Minor: 11, Patch: 2}.Compare(ServerVersion{Major: 2, Minor: 11}))
+		assert.Equal(tt, 0, ServerVersion{Major: 2, Minor: 13}.Compare(ServerVersion{Major: 2, Minor: 13}))
+		assert.Equal(tt, "2.11.2.0", ServerVersion{Major: 2, Minor: 11, Patch: 2}.String())
+	})
+
+	t.Run("it=selects the profile of the version", func(tt *testing.T) {
+		profiles := VersionProfiles{
+			{MinVersion: ServerVersion{Major: 2, Minor: 13}},
+			{MinVersion: ServerVersion{Major: 2, Minor: 9}},
+			{MinVersion: ServerVersion{Major: 2, Minor: 11, Patch: 2}},
+		}
+		for _, testCase := range []struct {
+			version  ServerVersion
+			expected ServerVersion
+		}{
+			{ServerVersion{Major: 2, Minor: 9, Patch: 1}, ServerVersion{Major: 2, Minor: 9}},
+			{ServerVersion{Major: 2, Minor: 11, Patch: 1}, ServerVersion{Major: 2, Minor: 9}},
+			{ServerVersion{Major: 2, Minor: 11, Patch: 2}, ServerVersion{Major: 2, Minor: 11, Patch: 2}},
+			{ServerVersion{Major: 2, Minor: 15}, ServerVersion{Major: 2, Minor: 13}},
+		} {
+			profile, ok := profiles.Select(testCase.version)
+			if assert.True(tt, ok, "version %s", testCase.version) {
+				assert.Equal(tt, testCase.expected, profile.MinVersion)
+			}
+		}
+		_, ok := profiles.Select(ServerVersion{Major: 2, Minor: 8})
+		assert.False(tt, ok)
+	})
+
+}
+
+func TestVersionProfiles(t *testing.T) {
+
+	legacy := NewLoadedServiceRegistry()
+	legacy.Register(string((&ybApi.ListMastersRequestPB{}).ProtoReflect().Descriptor().FullName()),
+		"ListMasters", "yb.master.LegacyMasterService")
+	profiles := []VersionProfile{
+		{MinVersion: ServerVersion{Major: 2, Minor: 9}, Registry: legacy},
+		{MinVersion: ServerVersion{Major: 2, Minor: 11, Patch: 2}},
+	}
+
+	for _, testCase := range []struct {
+		version  string
+		expected ServerVersion
+		service  string
+	}{
+		{"2.9.1.0-b12", ServerVersion{Major: 2, Minor: 9, Patch: 1}, "yb.master.LegacyMasterService"},
+		{"2.13.0.1", ServerVersion{Major: 2, Minor: 13, Revision: 1}, "yb.master.MasterService"},
+	} {
+		testCase := testCase
+		t.Run("it=uses the profile of the server version "+testCase.version, func(tt *testing.T) {
+			chanMethods := make(chan *ybApi.RemoteMethodPB, 1)
+			c := testClient(tt, &configs.YBClientConfig{
+				MasterHostPort: []string{testVersionedMaster(tt, testCase.version, chanMethods)},
+				OpTimeout:      time.Second,
+			}, func(c YBClient) YBClient {
+				return c.WithVersionProfiles(profiles...)
+			})
+			version, ok := c.ServerVersion()
+			if assert.True(tt, ok) {
+				assert.Equal(tt, testCase.expected, version)
+			}
+			assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+			assert.Equal(tt, testCase.service, (<-chanMethods).GetServiceName())
+		})
+	}
+
+	t.Run("it=reports the version while detecting it", func(tt *testing.T) {
+		chanMethods := make(chan *ybApi.RemoteMethodPB, 1)
+		c, err := NewDefaultConnector().
+			WithLogger(hclog.NewNullLogger()).
+			WithVersionProfiles(profiles...).
+			Connect(&configs.YBSingleNodeClientConfig{
+				MasterHostPort: testVersionedMaster(tt, "2.9.1.0", chanMethods),
+				OpTimeout:      1000,
+			})
+		if !assert.Nil(tt, err) {
+			return
+		}
+		defer c.Close()
+		// the version is read while it is being detected:
+		chanDone := make(chan struct{})
+		go func() {
+			defer close(chanDone)
+			for {
+				select {
+				case <-c.OnConnected():
+					return
+				default:
+					c.ServerVersion()
+				}
+			}
+		}()
+		<-chanDone
+		_, ok := c.ServerVersion()
+		assert.True(tt, ok)
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+		assert.Equal(tt, "yb.master.LegacyMasterService", (<-chanMethods).GetServiceName())
+	})
+
+	t.Run("it=detects the version with the built-in profiles", func(tt *testing.T) {
+		chanMethods := make(chan *ybApi.RemoteMethodPB, 1)
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{testVersionedMaster(tt, "2.13.0.1", chanMethods)},
+			OpTimeout:      time.Second,
+		})
+		_, ok := c.ServerVersion()
+		assert.True(tt, ok)
+	})
+
+}
+
+func TestDefaultVersionProfiles(t *testing.T) {
+
+	for _, testCase := range []struct {
+		version string
+		backup  string
+	}{
+		{"2.9.1.0-b12", "yb.master.MasterBackupService"},
+		{"2.11.0.0", "yb.master.MasterBackupService"},
+		{"2.11.2.0-b89", "yb.master.MasterBackupService"},
+		{"2.13.0.1", "yb.master.MasterBackup"},
+		{"2.15.0.0", "yb.master.MasterBackup"},
+	} {
+		testCase := testCase
+		t.Run("it=uses the service names of "+testCase.version, func(tt *testing.T) {
+			chanMethods := make(chan *ybApi.RemoteMethodPB, 1)
+			c := testClient(tt, &configs.YBClientConfig{
+				MasterHostPort: []string{testVersionedMaster(tt, testCase.version, chanMethods)},
+				OpTimeout:      time.Second,
+			})
+			assert.Nil(tt, c.Execute(&ybApi.CreateSnapshotRequestPB{}, &ybApi.CreateSnapshotResponsePB{}))
+			assert.Equal(tt, testCase.backup, (<-chanMethods).GetServiceName())
+			// the services split out of MasterService keep the name:
+			assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{}))
+			assert.Equal(tt, "yb.master.MasterService", (<-chanMethods).GetServiceName())
+		})
+	}
+
+	t.Run("it=resolves the methods of renamed services", func(tt *testing.T) {
+		registry := DefaultVersionProfiles().registry(ServerVersion{Major: 2, Minor: 9}, NewLoadedServiceRegistry())
+		assert.Equal(tt, "yb.master.MasterBackupService", registry.Get(&ybApi.CreateSnapshotRequestPB{}).Service())
+		method, ok := registry.LookupMethod("yb.master.MasterBackupService", "CreateSnapshot")
+		if assert.True(tt, ok) {
+			assert.Equal(tt, (&ybApi.CreateSnapshotResponsePB{}).ProtoReflect().Descriptor().FullName(), method.Output().FullName())
+		}
+		candidates := registry.Candidates(&ybApi.CreateSnapshotRequestPB{})
+		if assert.Equal(tt, 1, len(candidates)) {
+			assert.Equal(tt, "yb.master.MasterBackupService", candidates[0].Service())
+			assert.Equal(tt, (&ybApi.CreateSnapshotResponsePB{}).ProtoReflect().Descriptor().FullName(), candidates[0].OutputType())
+		}
+		for _, info := range registry.Methods() {
+			assert.NotEqual(tt, "yb.master.MasterBackup", info.Service())
+		}
+	})
+
+	t.Run("it=executes renamed methods by name", func(tt *testing.T) {
+		chanMethods := make(chan *ybApi.RemoteMethodPB, 1)
+		c := testClient(tt, &configs.YBClientConfig{
+			MasterHostPort: []string{testVersionedMaster(tt, "2.11.2.0", chanMethods)},
+			OpTimeout:      time.Second,
+		})
+		assert.Nil(tt, c.ExecuteMethod(context.Background(), NewServiceInfo("yb.master.MasterBackupService", "ListSnapshots"),
+			&ybApi.ListSnapshotsRequestPB{}, &ybApi.ListSnapshotsResponsePB{}))
+		assert.Equal(tt, "yb.master.MasterBackupService", (<-chanMethods).GetServiceName())
+	})
+
+}
@@ -0,0 +1,91 @@
+package configs
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// AddressTranslatorFunc translates an advertised host:port address
+// to the address reachable by the client.
+type AddressTranslatorFunc func(address string) string
+
+// AddressRewriteRule rewrites advertised addresses matching the rule.
+type AddressRewriteRule struct {
+	// Match is an IP network in CIDR notation, for example 10.0.0.0/8,
+	// or a host name. A host name starting with a dot matches all hosts in the domain,
+	// for example .pod.cluster.local.
+	Match string
+	// Host replaces the host of the matching address, the host is kept if empty.
+	Host string
+	// Port replaces the port of the matching address, the port is kept if 0.
+	Port uint16
+}
+
+// matches returns true if the rule matches the host.
+func (r *AddressRewriteRule) matches(host string) bool {
+	if strings.Contains(r.Match, "/") {
+		_, network, err := net.ParseCIDR(r.Match)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && network.Contains(ip)
+	}
+	if strings.HasPrefix(r.Match, ".") {
+		return strings.HasSuffix(host, r.Match)
+	}
+	return host == r.Match
+}
+
+func (r *AddressRewriteRule) validate() error {
+	if r.Match == "" {
+		return fmt.Errorf("address rewrite rule match is required")
+	}
+	if strings.Contains(r.Match, "/") {
+		if _, _, err := net.ParseCIDR(r.Match); err != nil {
+			return fmt.Errorf("address rewrite rule match '%s' is not a valid CIDR: %v", r.Match, err)
+		}
+	}
+	if r.Host == "" && r.Port == 0 {
+		return fmt.Errorf("address rewrite rule for '%s' requires a host or a port", r.Match)
+	}
+	return nil
+}
+
+// TranslateAddress translates an address advertised by a master or a tablet server
+// to the address reachable by the client. The address is translated in order by:
+//   - the static address map, matching the host:port first and the host second,
+//   - the first matching address rewrite rule,
+//   - the address translator.
+//
+// Addresses without translation are returned unchanged.
+func (c *YBClientConfig) TranslateAddress(address string) string {
+	if mapped, ok := c.AddressMap[address]; ok {
+		address = mapped
+	} else if host, port, err := net.SplitHostPort(address); err == nil {
+		if mapped, ok := c.AddressMap[host]; ok {
+			address = net.JoinHostPort(mapped, port)
+		}
+	}
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		for _, rule := range c.AddressRewriteRules {
+			if !rule.matches(host) {
+				continue
+			}
+			if rule.Host != "" {
+				host = rule.Host
+			}
+			if rule.Port != 0 {
+				port = strconv.Itoa(int(rule.Port))
+			}
+			address = net.JoinHostPort(host, port)
+			break
+		}
+	}
+	if c.AddressTranslator != nil {
+		address = c.AddressTranslator(address)
+	}
+	return address
+}
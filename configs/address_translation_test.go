@@ -0,0 +1,65 @@
+package configs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateAddress(t *testing.T) {
+
+	config := &YBClientConfig{
+		AddressMap: map[string]string{
+			"yb-master-1:7100": "127.0.0.1:17100",
+			"yb-master-2":      "127.0.0.2",
+		},
+		AddressRewriteRules: []AddressRewriteRule{
+			{Match: "10.0.0.0/8", Host: "bastion.example.com"},
+			{Match: ".pod.cluster.local", Port: 7200},
+			{Match: "yb-tserver-1", Host: "127.0.0.3", Port: 19100},
+		},
+	}
+
+	for _, testCase := range []struct {
+		address  string
+		expected string
+	}{
+		{address: "yb-master-1:7100", expected: "127.0.0.1:17100"},
+		{address: "yb-master-2:7100", expected: "127.0.0.2:7100"},
+		{address: "10.1.2.3:9100", expected: "bastion.example.com:9100"},
+		{address: "yb-master-0.pod.cluster.local:7100", expected: "yb-master-0.pod.cluster.local:7200"},
+		{address: "yb-tserver-1:9100", expected: "127.0.0.3:19100"},
+		{address: "192.168.0.1:7100", expected: "192.168.0.1:7100"},
+		{address: "not-an-address", expected: "not-an-address"},
+	} {
+		t.Run("it=translates "+testCase.address, func(tt *testing.T) {
+			assert.Equal(tt, testCase.expected, config.TranslateAddress(testCase.address))
+		})
+	}
+
+	t.Run("it=applies the translator after the rules", func(tt *testing.T) {
+		config := &YBClientConfig{
+			AddressMap: map[string]string{"yb-master-1": "yb-master-1.example.com"},
+			AddressTranslator: func(address string) string {
+				return strings.ToUpper(address)
+			},
+		}
+		assert.Equal(tt, "YB-MASTER-1.EXAMPLE.COM:7100", config.TranslateAddress("yb-master-1:7100"))
+	})
+
+	t.Run("it=validates the rules", func(tt *testing.T) {
+		for _, rule := range []AddressRewriteRule{
+			{Host: "127.0.0.1"},
+			{Match: "10.0.0.0/33", Host: "127.0.0.1"},
+			{Match: "10.0.0.0/8"},
+		} {
+			config := &YBClientConfig{
+				MasterHostPort:      []string{"127.0.0.1:7100"},
+				AddressRewriteRules: []AddressRewriteRule{rule},
+			}
+			assert.NotNil(tt, config.Validate())
+		}
+	})
+
+}
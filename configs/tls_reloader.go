@@ -0,0 +1,97 @@
+package configs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateReloader loads the client certificate from files
+// and reloads it when the files change on disk.
+// The files are checked at most once per check interval,
+// during the TLS handshake.
+type CertificateReloader struct {
+	certPath      string
+	keyPath       string
+	checkInterval time.Duration
+
+	lock        *sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+	lastErr     error
+}
+
+// NewCertificateReloader loads the certificate and returns the reloader.
+// Returns an error if the certificate can't be loaded.
+func NewCertificateReloader(certPath, keyPath string, checkInterval time.Duration) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		certPath:      certPath,
+		keyPath:       keyPath,
+		checkInterval: checkInterval,
+		lock:          &sync.Mutex{},
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate returns the current certificate,
+// to be used as the tls.Config GetClientCertificate callback.
+func (r *CertificateReloader) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// Certificate returns the current certificate, reloads it if the files changed.
+// If reloading fails, the previously loaded certificate is returned
+// and the error is available from LastError.
+func (r *CertificateReloader) Certificate() *tls.Certificate {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if time.Since(r.lastCheck) >= r.checkInterval {
+		r.lastErr = r.reloadUnsafe()
+	}
+	return r.cert
+}
+
+// LastError returns the error of the last reload attempt, if any.
+func (r *CertificateReloader) LastError() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.lastErr
+}
+
+func (r *CertificateReloader) reload() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.reloadUnsafe()
+}
+
+func (r *CertificateReloader) reloadUnsafe() error {
+	r.lastCheck = time.Now()
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return fmt.Errorf("TLS configuration error, could not stat cert file, reason: %v", err)
+	}
+	keyInfo, err := os.Stat(r.keyPath)
+	if err != nil {
+		return fmt.Errorf("TLS configuration error, could not stat key file, reason: %v", err)
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		// the files may be in the middle of a rotation,
+		// modification times are not updated so the next check retries:
+		return fmt.Errorf("TLS configuration error, could not load X509 key pair, reason: %v", err)
+	}
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	return nil
+}
@@ -0,0 +1,139 @@
+package configs
+
+import (
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/radekg/yugabyte-db-go-client/testutils/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSConfig(t *testing.T) {
+
+	ca := common.NewTestCertificateAuthority(t)
+
+	t.Run("it=does not configure TLS by default", func(tt *testing.T) {
+		tlsConfig, err := (&YBClientConfig{}).TLSConfig()
+		assert.Nil(tt, err)
+		assert.Nil(tt, tlsConfig)
+	})
+
+	t.Run("it=configures server authentication with system roots", func(tt *testing.T) {
+		tlsConfig, err := (&YBClientConfig{TLS: true, TLSServerName: "yb-master.example.com"}).TLSConfig()
+		assert.Nil(tt, err)
+		if assert.NotNil(tt, tlsConfig) {
+			assert.Nil(tt, tlsConfig.RootCAs)
+			assert.Nil(tt, tlsConfig.GetClientCertificate)
+			assert.Equal(tt, "yb-master.example.com", tlsConfig.ServerName)
+			assert.False(tt, tlsConfig.InsecureSkipVerify)
+		}
+	})
+
+	t.Run("it=configures server authentication with a CA file", func(tt *testing.T) {
+		config := &YBClientConfig{
+			MasterHostPort:    []string{"127.0.0.1:7100"},
+			TLSCaCertFilePath: ca.WriteCert(tt, tt.TempDir()),
+		}
+		assert.Nil(tt, config.Validate())
+		tlsConfig, err := config.TLSConfig()
+		assert.Nil(tt, err)
+		if assert.NotNil(tt, tlsConfig) && assert.NotNil(tt, tlsConfig.RootCAs) {
+			leaf, err := x509.ParseCertificate(ca.IssueTLS(tt, "server", "127.0.0.1").Certificate[0])
+			assert.Nil(tt, err)
+			_, err = leaf.Verify(x509.VerifyOptions{Roots: tlsConfig.RootCAs})
+			assert.Nil(tt, err)
+			assert.Nil(tt, tlsConfig.GetClientCertificate)
+		}
+	})
+
+	t.Run("it=requires a CA file with system roots", func(tt *testing.T) {
+		config := &YBClientConfig{
+			MasterHostPort: []string{"127.0.0.1:7100"},
+			TLSSystemRoots: true,
+		}
+		assert.NotNil(tt, config.Validate())
+	})
+
+	t.Run("it=configures skip verify", func(tt *testing.T) {
+		tlsConfig, err := (&YBClientConfig{TLS: true, TLSSkipVerify: true}).TLSConfig()
+		assert.Nil(tt, err)
+		if assert.NotNil(tt, tlsConfig) {
+			assert.True(tt, tlsConfig.InsecureSkipVerify)
+		}
+	})
+
+	t.Run("it=enables TLS with skip verify only", func(tt *testing.T) {
+		config := &YBClientConfig{TLSSkipVerify: true}
+		assert.True(tt, config.TLSEnabled())
+		tlsConfig, err := config.TLSConfig()
+		assert.Nil(tt, err)
+		if assert.NotNil(tt, tlsConfig) {
+			assert.True(tt, tlsConfig.InsecureSkipVerify)
+		}
+	})
+
+	t.Run("it=enables TLS with server name only", func(tt *testing.T) {
+		config := &YBClientConfig{TLSServerName: "yb-master.example.com"}
+		assert.True(tt, config.TLSEnabled())
+		tlsConfig, err := config.TLSConfig()
+		assert.Nil(tt, err)
+		if assert.NotNil(tt, tlsConfig) {
+			assert.Equal(tt, "yb-master.example.com", tlsConfig.ServerName)
+			assert.False(tt, tlsConfig.InsecureSkipVerify)
+		}
+	})
+
+	t.Run("it=fails with missing client certificate files", func(tt *testing.T) {
+		_, err := (&YBClientConfig{
+			TLSCertFilePath: "/does/not/exist.pem",
+			TLSKeyFilePath:  "/does/not/exist.key",
+		}).TLSConfig()
+		assert.NotNil(tt, err)
+	})
+
+}
+
+func TestCertificateReloader(t *testing.T) {
+
+	ca := common.NewTestCertificateAuthority(t)
+	dir := t.TempDir()
+	certPath, keyPath := ca.IssueFiles(t, dir, "client-1")
+
+	reloader, err := NewCertificateReloader(certPath, keyPath, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	leafCommonName := func(tt *testing.T) string {
+		cert, err := reloader.GetClientCertificate(nil)
+		assert.Nil(tt, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.Nil(tt, err)
+		return leaf.Subject.CommonName
+	}
+
+	t.Run("it=loads the certificate", func(tt *testing.T) {
+		assert.Equal(tt, "client-1", leafCommonName(tt))
+	})
+
+	t.Run("it=reloads the rotated certificate", func(tt *testing.T) {
+		ca.IssueFiles(tt, dir, "client-2")
+		// make sure the modification time changes on file systems with coarse timestamps:
+		future := time.Now().Add(time.Minute)
+		assert.Nil(tt, os.Chtimes(certPath, future, future))
+		assert.Nil(tt, os.Chtimes(keyPath, future, future))
+		assert.Equal(tt, "client-2", leafCommonName(tt))
+		assert.Nil(tt, reloader.LastError())
+	})
+
+	t.Run("it=keeps the certificate when the rotated files are broken", func(tt *testing.T) {
+		assert.Nil(tt, os.WriteFile(keyPath, []byte("broken"), 0600))
+		future := time.Now().Add(time.Minute * 2)
+		assert.Nil(tt, os.Chtimes(keyPath, future, future))
+		assert.Equal(tt, "client-2", leafCommonName(tt))
+		assert.NotNil(tt, reloader.LastError())
+	})
+
+}
@@ -21,11 +21,38 @@ type YBSingleNodeClientConfig struct {
 	MasterHostPort string
 	TLSConfig      *tls.Config
 	OpTimeout      uint32
+	MaxFrameSize   uint32
+	// MaxInFlightRequests limits the number of requests awaiting a response
+	// on the connection. Zero means the default, NoInFlightLimit disables the limit.
+	MaxInFlightRequests int32
 }
 
+// MasterDiscoveryMode defines how the client learns about master addresses.
+type MasterDiscoveryMode string
+
 const (
+	// MasterDiscoverySeedOnly uses only the configured master addresses.
+	MasterDiscoverySeedOnly MasterDiscoveryMode = "seed-only"
+	// MasterDiscoveryAuto periodically refreshes master addresses from the connected master
+	// and merges them with the configured master addresses.
+	MasterDiscoveryAuto MasterDiscoveryMode = "auto"
+)
+
+const (
+	// DefaultDialTimeout is the default timeout of establishing a connection.
+	DefaultDialTimeout = time.Second * 10
+	// DefaultKeepAlive is the default TCP keepalive period.
+	DefaultKeepAlive = time.Second * 15
+	// DefaultMasterRefreshInterval is the default master addresses refresh interval.
+	DefaultMasterRefreshInterval = time.Second * 30
 	// DefaultMaxExecuteRetries is the default maximum number of retries for a failed execute.
 	DefaultMaxExecuteRetries int32 = 10
+	// DefaultMaxFrameSize is the default maximum size of a single response frame.
+	// Matches the default YugabyteDB rpc_max_message_size.
+	DefaultMaxFrameSize uint32 = 255 * 1024 * 1024
+	// DefaultMaxInFlightRequests is the default maximum number of requests
+	// awaiting a response on a single connection.
+	DefaultMaxInFlightRequests int32 = 256
 	// DefaultMaxReconnectAttempts is the default max reconnect attempts value.
 	DefaultMaxReconnectAttempts int32 = 10
 	// DefaultOpTimeout is the default operation timeout value.
@@ -34,9 +61,16 @@ const (
 	DefaultReconnectRetryInterval = time.Second
 	// DefaultRetryInterval is the default retry interval value.
 	DefaultRetryInterval = time.Second
+	// DefaultRetryMaxInterval is the default maximum interval between retries.
+	DefaultRetryMaxInterval = time.Second * 30
+	// DefaultTLSCertReloadInterval is the default interval of checking
+	// the client certificate files for changes.
+	DefaultTLSCertReloadInterval = time.Second * 10
 
 	// NoExecuteRetry is a magic value disabling retry of failed execute.
 	NoExecuteRetry int32 = -1
+	// NoInFlightLimit is a magic value disabling the in-flight requests limit.
+	NoInFlightLimit int32 = -1
 	// NoReconnectAttempts is a magic value disabling reconnect attempts.
 	NoReconnectAttempts int32 = -1
 )
@@ -45,22 +79,73 @@ const (
 type YBClientConfig struct {
 	tlsConfig *tls.Config
 
-	MasterHostPort         []string
-	OpTimeout              time.Duration
-	MaxExecuteRetries      int32
+	MasterHostPort []string
+	// AddressMap maps advertised host:port addresses or hosts to reachable addresses or hosts.
+	AddressMap map[string]string
+	// AddressRewriteRules rewrite advertised addresses, the first matching rule applies.
+	AddressRewriteRules []AddressRewriteRule
+	// AddressTranslator translates advertised addresses, applied after the map and the rules.
+	AddressTranslator AddressTranslatorFunc
+	// ExpectedClusterUUID pins the client to the cluster, the client fails to connect
+	// to a master leader reporting a different cluster UUID in the cluster config.
+	ExpectedClusterUUID string
+	// ExpectedMasterUUIDs pins the client to the masters, the client fails to connect
+	// to a master leader with an instance UUID not in the list.
+	ExpectedMasterUUIDs []string
+
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	MasterDiscovery       MasterDiscoveryMode
+	MasterRefreshInterval time.Duration
+	OpTimeout             time.Duration
+	MaxExecuteRetries     int32
+	MaxFrameSize          uint32
+	// MaxInFlightRequests limits the number of requests awaiting a response
+	// on a single connection, calls over the limit wait for a free slot.
+	MaxInFlightRequests    int32
 	MaxReconnectAttempts   int32
 	ReconnectRetryInterval time.Duration
 	RetryInterval          time.Duration
-	TLSCaCertFilePath      string
-	TLSCertFilePath        string
-	TLSKeyFilePath         string
+	RetryMaxInterval       time.Duration
+	RetryBudget            time.Duration
+	// TLS enables TLS without the CA cert file and the client certificate.
+	TLS                   bool
+	TLSCaCertFilePath     string
+	TLSCertFilePath       string
+	TLSCertReloadInterval time.Duration
+	TLSKeyFilePath        string
+	// TLSServerName overrides the server name used to verify the server certificate.
+	TLSServerName string
+	// TLSSkipVerify disables the server certificate verification.
+	// Use only in test environments.
+	TLSSkipVerify bool
+	// TLSSystemRoots trusts the system roots in addition to the CA cert file.
+	TLSSystemRoots bool
 }
 
 // WithDefaults applies defaults to unset values.
 func (c *YBClientConfig) WithDefaults() *YBClientConfig {
+	if c.DialTimeout == 0 {
+		c.DialTimeout = DefaultDialTimeout
+	}
+	if c.KeepAlive == 0 {
+		c.KeepAlive = DefaultKeepAlive
+	}
+	if c.MasterDiscovery == "" {
+		c.MasterDiscovery = MasterDiscoverySeedOnly
+	}
+	if c.MasterRefreshInterval == 0 {
+		c.MasterRefreshInterval = DefaultMasterRefreshInterval
+	}
 	if c.MaxExecuteRetries == 0 {
 		c.MaxExecuteRetries = DefaultMaxExecuteRetries
 	}
+	if c.MaxFrameSize == 0 {
+		c.MaxFrameSize = DefaultMaxFrameSize
+	}
+	if c.MaxInFlightRequests == 0 {
+		c.MaxInFlightRequests = DefaultMaxInFlightRequests
+	}
 	if c.MaxReconnectAttempts == 0 {
 		c.MaxReconnectAttempts = DefaultMaxReconnectAttempts
 	}
@@ -73,38 +158,74 @@ func (c *YBClientConfig) WithDefaults() *YBClientConfig {
 	if c.RetryInterval == 0 {
 		c.RetryInterval = DefaultRetryInterval
 	}
+	if c.RetryMaxInterval == 0 {
+		c.RetryMaxInterval = DefaultRetryMaxInterval
+	}
 	return c
 }
 
+// TLSEnabled returns true if the client connects with TLS.
+// Any TLS setting enables TLS, settings are never silently ignored.
+func (c *YBClientConfig) TLSEnabled() bool {
+	return c.TLS ||
+		c.TLSCaCertFilePath != "" ||
+		c.TLSCertFilePath != "" ||
+		c.TLSKeyFilePath != "" ||
+		c.TLSServerName != "" ||
+		c.TLSSkipVerify
+}
+
 // TLSConfig returns TLS config if TLS is configured.
+// Without a CA cert file, the server certificate is verified against the system roots.
+// The client certificate is optional, the client certificate files
+// are reloaded when they change on disk.
 func (c *YBClientConfig) TLSConfig() (*tls.Config, error) {
 
 	if c.tlsConfig != nil {
 		return c.tlsConfig, nil
 	}
 
-	if c.TLSCertFilePath != "" && c.TLSKeyFilePath != "" {
-		cfg := &tls.Config{}
+	if !c.TLSEnabled() {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSSkipVerify,
+	}
+
+	if c.TLSCaCertFilePath != "" {
 		cfg.RootCAs = x509.NewCertPool()
-		if c.TLSCaCertFilePath != "" {
-			caCertBytes, err := ioutil.ReadFile(c.TLSCaCertFilePath)
+		if c.TLSSystemRoots {
+			systemRoots, err := x509.SystemCertPool()
 			if err != nil {
-				return nil, fmt.Errorf("TLS configuration error, could not read ca cert file, reason: %v", err)
-			}
-			if ok := cfg.RootCAs.AppendCertsFromPEM(caCertBytes); !ok {
-				return nil, fmt.Errorf("TLS configuration error, could append root certificates from file")
+				return nil, fmt.Errorf("TLS configuration error, could not load system roots, reason: %v", err)
 			}
+			cfg.RootCAs = systemRoots
 		}
-		cert, err := tls.LoadX509KeyPair(c.TLSCertFilePath, c.TLSKeyFilePath)
+		caCertBytes, err := ioutil.ReadFile(c.TLSCaCertFilePath)
 		if err != nil {
-			return nil, fmt.Errorf("TLS configuration error, could not load X509 key pair, reason: %v", err)
+			return nil, fmt.Errorf("TLS configuration error, could not read ca cert file, reason: %v", err)
+		}
+		if ok := cfg.RootCAs.AppendCertsFromPEM(caCertBytes); !ok {
+			return nil, fmt.Errorf("TLS configuration error, could append root certificates from file")
 		}
-		cfg.Certificates = []tls.Certificate{cert}
-		c.tlsConfig = cfg
-		return c.tlsConfig, nil
 	}
 
-	return nil, nil
+	if c.TLSCertFilePath != "" && c.TLSKeyFilePath != "" {
+		reloadInterval := c.TLSCertReloadInterval
+		if reloadInterval == 0 {
+			reloadInterval = DefaultTLSCertReloadInterval
+		}
+		reloader, err := NewCertificateReloader(c.TLSCertFilePath, c.TLSKeyFilePath, reloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	c.tlsConfig = cfg
+	return c.tlsConfig, nil
 }
 
 // Validate validates the correctness of the configuration.
@@ -118,10 +239,8 @@ func (c *YBClientConfig) Validate() error {
 	if c.TLSKeyFilePath != "" && c.TLSCertFilePath == "" {
 		return fmt.Errorf("both --tls-cert-file-path and --tls-key-file-path are required")
 	}
-	if c.TLSCaCertFilePath != "" {
-		if c.TLSKeyFilePath == "" || c.TLSCertFilePath == "" {
-			return fmt.Errorf("both --tls-cert-file-path and --tls-key-file-path are required when --tls-ca-cert-file-path")
-		}
+	if c.TLSSystemRoots && c.TLSCaCertFilePath == "" {
+		return fmt.Errorf("--tls-system-roots requires --tls-ca-cert-file-path, system roots are used by default")
 	}
 	for _, path := range []string{c.TLSCertFilePath, c.TLSKeyFilePath, c.TLSCaCertFilePath} {
 		if path != "" {
@@ -134,6 +253,14 @@ func (c *YBClientConfig) Validate() error {
 			}
 		}
 	}
+	for _, rule := range c.AddressRewriteRules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	if c.MasterDiscovery != "" && c.MasterDiscovery != MasterDiscoverySeedOnly && c.MasterDiscovery != MasterDiscoveryAuto {
+		return fmt.Errorf("--master-discovery must be one of: %s, %s", MasterDiscoverySeedOnly, MasterDiscoveryAuto)
+	}
 	if c.OpTimeout.Milliseconds() < 0 {
 		return fmt.Errorf("--operation-timeout must be greater than 0")
 	}
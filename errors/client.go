@@ -2,6 +2,8 @@ package errors
 
 import (
 	"fmt"
+	"io"
+	"net"
 	"syscall"
 
 	goErrors "errors"
@@ -12,12 +14,28 @@ import (
 )
 
 const (
+	// ErrorMessageClosed is an error message.
+	ErrorMessageClosed = "client: closed"
+	// ErrorMessageClusterUUIDMismatch is an error message.
+	ErrorMessageClusterUUIDMismatch = "client: cluster UUID mismatch"
 	// ErrorMessageConnected is an error message.
 	ErrorMessageConnected = "client: connected"
 	// ErrorMessageConnecting is an error message.
 	ErrorMessageConnecting = "client: connecting"
+	// ErrorMessageConnectionClosed is an error message.
+	ErrorMessageConnectionClosed = "client: connection closed"
+	// ErrorMessageFrameTooLarge is an error message.
+	ErrorMessageFrameTooLarge = "client: frame too large"
+	// ErrorMessageInvalidJSONPayload is an error message.
+	ErrorMessageInvalidJSONPayload = "client: invalid JSON payload"
+	// ErrorMessageInvalidServerVersion is an error message.
+	ErrorMessageInvalidServerVersion = "client: invalid server version"
 	// ErrorMessageLeaderWaitTimeout is an error message.
 	ErrorMessageLeaderWaitTimeout = "client: leader wait timed out"
+	// ErrorMessageMasterUUIDMismatch is an error message.
+	ErrorMessageMasterUUIDMismatch = "client: master UUID mismatch"
+	// ErrorMessageMethodTypeMismatch is an error message.
+	ErrorMessageMethodTypeMismatch = "client: method type mismatch"
 	// ErrorMessageNoClient is an error message.
 	ErrorMessageNoClient = "client: no client"
 	// ErrorMessageNoLeader is an error message.
@@ -40,6 +58,10 @@ const (
 	ErrorMessageSendFailed = "client: send failed"
 	// ErrorMessageServiceError is an error message.
 	ErrorMessageServiceError = "client: service error"
+	// ErrorMessageTServerNotFound is an error message.
+	ErrorMessageTServerNotFound = "client: tablet server not found"
+	// ErrorMessageUnknownMethod is an error message.
+	ErrorMessageUnknownMethod = "client: unknown method"
 	// ErrorMessageUnprocessableResponse is an error message.
 	ErrorMessageUnprocessableResponse = "client: unprocessable response"
 )
@@ -52,6 +74,101 @@ type AbstractMasterErrorResponse interface {
 	GetError() *ybApi.MasterErrorPB
 }
 
+// ClosedError is returned by calls executed after the client has been closed
+// or while the client is shutting down.
+type ClosedError struct{}
+
+func (e *ClosedError) Error() string {
+	return ErrorMessageClosed
+}
+
+// ErrClosed is the closed client error, use with errors.Is.
+var ErrClosed error = &ClosedError{}
+
+// ClusterUUIDMismatchError is returned when the master leader
+// belongs to a cluster other than the expected one.
+type ClusterUUIDMismatchError struct {
+	Address  string
+	Expected string
+	Reported string
+}
+
+func (e *ClusterUUIDMismatchError) Error() string {
+	return fmt.Sprintf("%s: master %s reported cluster %q, expected %q", ErrorMessageClusterUUIDMismatch, e.Address, e.Reported, e.Expected)
+}
+
+// ConnectionClosedError is returned by calls executed on a connection
+// which stopped reading responses, the connection is never usable again.
+type ConnectionClosedError struct {
+	Cause error
+}
+
+func (e *ConnectionClosedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrorMessageConnectionClosed, e.Cause.Error())
+}
+
+// Unwrap returns the cause of the error.
+func (e *ConnectionClosedError) Unwrap() error {
+	return e.Cause
+}
+
+// FrameTooLargeError is returned when the server announces a response frame
+// larger than the configured maximum frame size.
+type FrameTooLargeError struct {
+	Size    uint32
+	MaxSize uint32
+}
+
+func (e *FrameTooLargeError) Error() string {
+	return fmt.Sprintf("%s: %d bytes vs maximum %d bytes", ErrorMessageFrameTooLarge, e.Size, e.MaxSize)
+}
+
+// InvalidJSONPayloadError is returned when a JSON payload
+// cannot be parsed as the input message of the method.
+type InvalidJSONPayloadError struct {
+	Cause     error
+	InputType protoreflect.FullName
+}
+
+func (e *InvalidJSONPayloadError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrorMessageInvalidJSONPayload, e.InputType, e.Cause.Error())
+}
+
+// InvalidServerVersionError is returned when a server version
+// cannot be parsed.
+type InvalidServerVersionError struct {
+	Version string
+}
+
+func (e *InvalidServerVersionError) Error() string {
+	return fmt.Sprintf("%s: %q", ErrorMessageInvalidServerVersion, e.Version)
+}
+
+// MasterUUIDMismatchError is returned when the instance UUID
+// of the master leader is not one of the expected master UUIDs.
+type MasterUUIDMismatchError struct {
+	Address  string
+	Expected []string
+	Reported string
+}
+
+func (e *MasterUUIDMismatchError) Error() string {
+	return fmt.Sprintf("%s: master %s reported instance %q, expected one of %q", ErrorMessageMasterUUIDMismatch, e.Address, e.Reported, e.Expected)
+}
+
+// MethodTypeMismatchError is returned when a message passed
+// to an explicit method is not the input or the output message of the method.
+type MethodTypeMismatchError struct {
+	Service  string
+	Method   string
+	Expected protoreflect.FullName
+	Received protoreflect.FullName
+}
+
+func (e *MethodTypeMismatchError) Error() string {
+	return fmt.Sprintf("%s: %s.%s expects '%s', received '%s'", ErrorMessageMethodTypeMismatch, e.Service, e.Method, e.Expected, e.Received)
+}
+
 // NoLeaderError represents a client without a leader error.
 type NoLeaderError struct{}
 
@@ -113,8 +230,31 @@ func (e *ReceiveError) Error() string {
 	return fmt.Sprintf("%s: %s", ErrorMessageReceiveFailed, e.Cause.Error())
 }
 
+// RequiresReconnect returns true when the connection can no longer
+// be used to read subsequent frames.
 func (e *ReceiveError) RequiresReconnect() bool {
-	return goErrors.Is(e.Cause, syscall.EPIPE)
+	if goErrors.Is(e.Cause, syscall.EPIPE) ||
+		goErrors.Is(e.Cause, net.ErrClosed) ||
+		goErrors.Is(e.Cause, io.EOF) ||
+		goErrors.Is(e.Cause, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var closedErr *ConnectionClosedError
+	if goErrors.As(e.Cause, &closedErr) {
+		return true
+	}
+	var frameErr *FrameTooLargeError
+	if goErrors.As(e.Cause, &frameErr) {
+		return true
+	}
+	// the client can't tell which call an unprocessable frame belonged to:
+	var unprocessableErr *UnprocessableResponseError
+	return goErrors.As(e.Cause, &unprocessableErr)
+}
+
+// Unwrap returns the cause of the error.
+func (e *ReceiveError) Unwrap() error {
+	return e.Cause
 }
 
 // RequiresReconnectError is an error indicating a need to reconnect.
@@ -155,6 +295,27 @@ func (e *SendError) Error() string {
 	return fmt.Sprintf("%s: %s", ErrorMessageSendFailed, e.Cause.Error())
 }
 
+// TServerNotFoundError is returned when the tablet server client cannot
+// resolve the target to a known tablet server.
+type TServerNotFoundError struct {
+	Target string
+}
+
+func (e *TServerNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrorMessageTServerNotFound, e.Target)
+}
+
+// UnknownMethodError is returned when a method called by name
+// is not found in the registered file descriptors.
+type UnknownMethodError struct {
+	Service string
+	Method  string
+}
+
+func (e *UnknownMethodError) Error() string {
+	return fmt.Sprintf("%s: %s.%s", ErrorMessageUnknownMethod, e.Service, e.Method)
+}
+
 // UnprocessableResponseError represents a client error where a fully read response
 // cannot be deserialized as a protobuf message.
 // This error usually implies that a retry is required.
@@ -9,7 +9,13 @@ require (
 	github.com/lib/pq v1.9.0
 	// dockertest/v3 used in tests:
 	github.com/ory/dockertest/v3 v3.8.1
+	github.com/prometheus/client_golang v1.11.0
 	github.com/radekg/yugabyte-db-go-proto/v2 v2.13.0
 	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel v1.3.0
+	// otel/sdk used in tests:
+	go.opentelemetry.io/otel/sdk v1.3.0
+	go.opentelemetry.io/otel/trace v1.3.0
+	golang.org/x/net v0.0.0-20201224014010-6772e930b67b
 	google.golang.org/protobuf v1.27.1
 )
@@ -0,0 +1,93 @@
+// Package wire implements the framing of the YugabyteDB RPC wire protocol
+// shared by the client, the replayer and the fake server.
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+)
+
+// ConnectionHeader is sent by the client before the first request.
+var ConnectionHeader = []byte{'Y', 'B', 1}
+
+// FrameLengthSize is the size of the total frame length prefix.
+const FrameLengthSize = 4
+
+// ReadFrame reads a single length-delimited frame from the reader.
+// Every frame starts with a 4 bytes big endian total length, followed by
+// exactly that many bytes of the header and the payload, per:
+// https://github.com/yugabyte/yugabyte-db/blob/v2.7.2/java/yb-client/src/main/java/org/yb/client/CallResponse.java#L71
+// The returned slice contains the frame without the length prefix.
+// Frames larger than maxFrameSize are refused before being read.
+func ReadFrame(reader io.Reader, maxFrameSize uint32) ([]byte, error) {
+	lengthBuf := make([]byte, FrameLengthSize)
+	if _, err := io.ReadFull(reader, lengthBuf); err != nil {
+		return nil, err
+	}
+	frameLength := binary.BigEndian.Uint32(lengthBuf)
+	if frameLength > maxFrameSize {
+		return nil, &errors.FrameTooLargeError{
+			Size:    frameLength,
+			MaxSize: maxFrameSize,
+		}
+	}
+	frame := make([]byte, frameLength)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// WriteFrame writes the varint length-delimited header followed by the body,
+// prefixed with the total frame length.
+func WriteFrame(writer io.Writer, header, body []byte) error {
+	frame := AppendLengthDelimited(make([]byte, FrameLengthSize), header)
+	frame = append(frame, body...)
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-FrameLengthSize))
+	_, err := writer.Write(frame)
+	return err
+}
+
+// AppendLengthDelimited appends the uvarint length of the data followed by the data.
+func AppendLengthDelimited(buf, data []byte) []byte {
+	varint := make([]byte, binary.MaxVarintLen32)
+	buf = append(buf, varint[0:binary.PutUvarint(varint, uint64(len(data)))]...)
+	return append(buf, data...)
+}
+
+// SplitHeader returns the length-delimited header of the frame
+// and the bytes following the header.
+func SplitHeader(frame []byte) ([]byte, []byte, error) {
+	reader := bytes.NewBuffer(frame)
+	headerLength, err := utils.ReadUvarint32(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("header length read failed: %v", err)
+	}
+	if uint64(reader.Len()) < headerLength {
+		return nil, nil, fmt.Errorf("header incomplete: %d bytes vs expected %d", reader.Len(), headerLength)
+	}
+	header := reader.Next(int(headerLength))
+	return header, reader.Bytes(), nil
+}
+
+// SplitRequest returns the request header and the request payload of the request frame.
+func SplitRequest(frame []byte) ([]byte, []byte, error) {
+	header, rest, err := SplitHeader(frame)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := bytes.NewBuffer(rest)
+	payloadLength, err := utils.ReadUvarint32(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("payload length read failed: %v", err)
+	}
+	if uint64(reader.Len()) < payloadLength {
+		return nil, nil, fmt.Errorf("payload incomplete: %d bytes vs expected %d", reader.Len(), payloadLength)
+	}
+	return header, reader.Next(int(payloadLength)), nil
+}
@@ -0,0 +1,92 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+const testMaxFrameSize = 1024 * 1024
+
+func testRequestFrame(t *testing.T, callID int32, payload *ybApi.ListMastersRequestPB) []byte {
+	b := bytes.NewBuffer([]byte{})
+	requestHeader := &ybApi.RequestHeader{
+		CallId: utils.PInt32(callID),
+	}
+	if err := utils.WriteMessages(b, requestHeader, payload); err != nil {
+		t.Fatalf("failed writing request frame: '%v'", err)
+	}
+	return b.Bytes()
+}
+
+func TestReadFrame(t *testing.T) {
+
+	t.Run("it=reads exactly one frame", func(tt *testing.T) {
+		frame1 := testRequestFrame(tt, 1, &ybApi.ListMastersRequestPB{})
+		frame2 := testRequestFrame(tt, 2, &ybApi.ListMastersRequestPB{})
+		reader := bytes.NewReader(append(frame1, frame2...))
+		read1, err := ReadFrame(reader, testMaxFrameSize)
+		assert.Nil(tt, err)
+		assert.Equal(tt, frame1[FrameLengthSize:], read1)
+		read2, err := ReadFrame(reader, testMaxFrameSize)
+		assert.Nil(tt, err)
+		assert.Equal(tt, frame2[FrameLengthSize:], read2)
+		_, err = ReadFrame(reader, testMaxFrameSize)
+		assert.Equal(tt, io.EOF, err)
+	})
+
+	t.Run("it=refuses frames above maximum size", func(tt *testing.T) {
+		frame := testRequestFrame(tt, 1, &ybApi.ListMastersRequestPB{})
+		_, err := ReadFrame(bytes.NewReader(frame), 1)
+		frameErr, ok := err.(*errors.FrameTooLargeError)
+		if assert.True(tt, ok, "expected *FrameTooLargeError") {
+			assert.Equal(tt, uint32(len(frame)-FrameLengthSize), frameErr.Size)
+		}
+		assert.True(tt, (&errors.ReceiveError{Cause: err}).RequiresReconnect())
+	})
+
+	t.Run("it=reports truncated frames", func(tt *testing.T) {
+		frame := testRequestFrame(tt, 1, &ybApi.ListMastersRequestPB{})
+		_, err := ReadFrame(bytes.NewReader(frame[0:len(frame)-1]), testMaxFrameSize)
+		assert.Equal(tt, io.ErrUnexpectedEOF, err)
+		lengthOnly := make([]byte, 2)
+		binary.BigEndian.PutUint16(lengthOnly, 1)
+		_, err = ReadFrame(bytes.NewReader(lengthOnly), testMaxFrameSize)
+		assert.Equal(tt, io.ErrUnexpectedEOF, err)
+	})
+
+}
+
+func TestWriteFrame(t *testing.T) {
+
+	t.Run("it=writes frames readable as requests", func(tt *testing.T) {
+		headerBytes, err := proto.Marshal(&ybApi.RequestHeader{CallId: utils.PInt32(7)})
+		assert.Nil(tt, err)
+		payload, err := proto.Marshal(&ybApi.ListMastersRequestPB{})
+		assert.Nil(tt, err)
+		buf := bytes.NewBuffer([]byte{})
+		assert.Nil(tt, WriteFrame(buf, headerBytes, AppendLengthDelimited(nil, payload)))
+		frame, err := ReadFrame(buf, testMaxFrameSize)
+		assert.Nil(tt, err)
+		readHeader, readPayload, err := SplitRequest(frame)
+		assert.Nil(tt, err)
+		assert.Equal(tt, headerBytes, readHeader)
+		assert.Equal(tt, payload, readPayload)
+	})
+
+	t.Run("it=reports incomplete requests", func(tt *testing.T) {
+		frame := AppendLengthDelimited(nil, []byte{1, 2, 3})
+		_, _, err := SplitRequest(frame[0 : len(frame)-1])
+		assert.NotNil(tt, err)
+		_, _, err = SplitRequest(append(frame, 10))
+		assert.NotNil(tt, err)
+	})
+
+}
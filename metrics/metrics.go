@@ -1,5 +1,7 @@
 package metrics
 
+import "time"
+
 // Callback represents a metrics callback
 // interface used internally by the client.
 // Provide your own implementation to retrieve metrics
@@ -33,3 +35,53 @@ func (p *noop) ClientMessageSendSuccess() {}
 func (p *noop) ClientReconnectAttempt()   {}
 func (p *noop) ClientReconnectFailure()   {}
 func (p *noop) ClientReconnectSuccess()   {}
+
+func (p *noop) RPCStarted(service, method, host string) {}
+func (p *noop) RPCFinished(event *RPCEvent)             {}
+
+// RPCOutcome is the outcome of a single RPC.
+type RPCOutcome string
+
+const (
+	// RPCOutcomeSuccess is a call which completed without an error.
+	RPCOutcomeSuccess RPCOutcome = "success"
+	// RPCOutcomeMasterError is a call which completed with a master error in the response.
+	RPCOutcomeMasterError RPCOutcome = "master_error"
+	// RPCOutcomeError is a call which failed with an error.
+	RPCOutcomeError RPCOutcome = "error"
+	// RPCOutcomeCanceled is a call abandoned because its context was done.
+	RPCOutcomeCanceled RPCOutcome = "canceled"
+)
+
+// RPCEvent describes a single RPC executed by a connected client.
+type RPCEvent struct {
+	Service  string
+	Method   string
+	Host     string
+	Duration time.Duration
+	Outcome  RPCOutcome
+	// ErrorClass is the class of the error from the client error classification,
+	// empty if the call did not fail with an error.
+	ErrorClass string
+	// ErrorCode is the master error code or the RPC error code, if any.
+	ErrorCode string
+}
+
+// RPCCallback is an optional interface of the metrics callback.
+// If the callback implements RPCCallback, the client reports
+// every RPC executed over the wire, including every retry.
+type RPCCallback interface {
+	// RPCStarted is called before the call is sent.
+	RPCStarted(service, method, host string)
+	// RPCFinished is called after every RPCStarted, when the call completes.
+	RPCFinished(event *RPCEvent)
+}
+
+// AsRPCCallback returns the callback as RPCCallback,
+// or a noop RPCCallback if the callback does not implement RPCCallback.
+func AsRPCCallback(callback Callback) RPCCallback {
+	if rpcCallback, ok := callback.(RPCCallback); ok {
+		return rpcCallback
+	}
+	return &noop{}
+}
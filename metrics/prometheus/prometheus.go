@@ -0,0 +1,162 @@
+// Package prometheus provides a metrics callback
+// exposing client metrics as Prometheus metrics.
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/radekg/yugabyte-db-go-client/metrics"
+)
+
+// DefaultNamespace is the default namespace of the collector metrics.
+const DefaultNamespace = "ybclient"
+
+// Opts configures the collector.
+type Opts struct {
+	// Namespace of the metrics, DefaultNamespace if empty.
+	Namespace string
+	// Subsystem of the metrics, optional.
+	Subsystem string
+	// ConstLabels are added to every metric.
+	ConstLabels prometheus.Labels
+	// Buckets of the RPC duration histogram in seconds,
+	// prometheus.DefBuckets if empty.
+	Buckets []float64
+}
+
+// Collector is a metrics callback collecting the client metrics.
+// The collector implements prometheus.Collector and must be registered
+// with a Prometheus registry.
+type Collector struct {
+	bytesReceived prometheus.Counter
+	bytesSent     prometheus.Counter
+	connects      prometheus.Counter
+	errors        prometheus.Counter
+	messages      *prometheus.CounterVec
+	reconnects    *prometheus.CounterVec
+	rpcDuration   *prometheus.HistogramVec
+	rpcInFlight   *prometheus.GaugeVec
+}
+
+// NewCollector returns a new collector configured with the options.
+func NewCollector(opts Opts) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = DefaultNamespace
+	}
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = prometheus.DefBuckets
+	}
+	counterOpts := func(name, help string) prometheus.CounterOpts {
+		return prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: opts.ConstLabels,
+		}
+	}
+	return &Collector{
+		bytesReceived: prometheus.NewCounter(counterOpts("received_bytes_total",
+			"Number of bytes received from the servers.")),
+		bytesSent: prometheus.NewCounter(counterOpts("sent_bytes_total",
+			"Number of bytes sent to the servers.")),
+		connects: prometheus.NewCounter(counterOpts("connects_total",
+			"Number of established connections.")),
+		errors: prometheus.NewCounter(counterOpts("errors_total",
+			"Number of client errors.")),
+		messages: prometheus.NewCounterVec(counterOpts("messages_total",
+			"Number of sent messages by result."), []string{"result"}),
+		reconnects: prometheus.NewCounterVec(counterOpts("reconnects_total",
+			"Number of reconnect attempts, successes and failures."), []string{"result"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "rpc_duration_seconds",
+			Help:        "Duration of RPCs by service, method, host, outcome and error class.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.Buckets,
+		}, []string{"service", "method", "host", "outcome", "error_class"}),
+		rpcInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "rpc_in_flight",
+			Help:        "Number of RPCs in flight by service, method and host.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"service", "method", "host"}),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.bytesReceived,
+		c.bytesSent,
+		c.connects,
+		c.errors,
+		c.messages,
+		c.reconnects,
+		c.rpcDuration,
+		c.rpcInFlight,
+	}
+}
+
+// -- prometheus collector interface
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+// -- metrics callback interface
+
+func (c *Collector) ClientBytesReceived(n int) {
+	c.bytesReceived.Add(float64(n))
+}
+func (c *Collector) ClientBytesSent(n int) {
+	c.bytesSent.Add(float64(n))
+}
+func (c *Collector) ClientConnect() {
+	c.connects.Inc()
+}
+func (c *Collector) ClientError() {
+	c.errors.Inc()
+}
+func (c *Collector) ClientMessageSendFailure() {
+	c.messages.WithLabelValues("failure").Inc()
+}
+func (c *Collector) ClientMessageSendSuccess() {
+	c.messages.WithLabelValues("success").Inc()
+}
+func (c *Collector) ClientReconnectAttempt() {
+	c.reconnects.WithLabelValues("attempt").Inc()
+}
+func (c *Collector) ClientReconnectFailure() {
+	c.reconnects.WithLabelValues("failure").Inc()
+}
+func (c *Collector) ClientReconnectSuccess() {
+	c.reconnects.WithLabelValues("success").Inc()
+}
+
+// -- metrics RPC callback interface
+
+func (c *Collector) RPCStarted(service, method, host string) {
+	c.rpcInFlight.WithLabelValues(service, method, host).Inc()
+}
+func (c *Collector) RPCFinished(event *metrics.RPCEvent) {
+	c.rpcInFlight.WithLabelValues(event.Service, event.Method, event.Host).Dec()
+	c.rpcDuration.WithLabelValues(event.Service, event.Method, event.Host,
+		string(event.Outcome), event.ErrorClass).Observe(event.Duration.Seconds())
+}
+
+var (
+	_ metrics.Callback     = (*Collector)(nil)
+	_ metrics.RPCCallback  = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
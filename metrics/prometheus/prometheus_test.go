@@ -0,0 +1,61 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/radekg/yugabyte-db-go-client/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+
+	t.Run("it=registers with a registry", func(tt *testing.T) {
+		registry := prometheus.NewPedanticRegistry()
+		assert.Nil(tt, registry.Register(NewCollector(Opts{})))
+	})
+
+	t.Run("it=tracks in flight RPCs and latency", func(tt *testing.T) {
+		collector := NewCollector(Opts{Buckets: []float64{0.1, 1}})
+		collector.RPCStarted("yb.master.MasterService", "ListTables", "127.0.0.1:7100")
+		collector.RPCStarted("yb.master.MasterService", "ListTables", "127.0.0.1:7100")
+		collector.RPCFinished(&metrics.RPCEvent{
+			Service:  "yb.master.MasterService",
+			Method:   "ListTables",
+			Host:     "127.0.0.1:7100",
+			Duration: time.Millisecond * 500,
+			Outcome:  metrics.RPCOutcomeSuccess,
+		})
+		assert.Nil(tt, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP ybclient_rpc_in_flight Number of RPCs in flight by service, method and host.
+# TYPE ybclient_rpc_in_flight gauge
+ybclient_rpc_in_flight{host="127.0.0.1:7100",method="ListTables",service="yb.master.MasterService"} 1
+# HELP ybclient_rpc_duration_seconds Duration of RPCs by service, method, host, outcome and error class.
+# TYPE ybclient_rpc_duration_seconds histogram
+ybclient_rpc_duration_seconds_bucket{error_class="",host="127.0.0.1:7100",method="ListTables",outcome="success",service="yb.master.MasterService",le="0.1"} 0
+ybclient_rpc_duration_seconds_bucket{error_class="",host="127.0.0.1:7100",method="ListTables",outcome="success",service="yb.master.MasterService",le="1"} 1
+ybclient_rpc_duration_seconds_bucket{error_class="",host="127.0.0.1:7100",method="ListTables",outcome="success",service="yb.master.MasterService",le="+Inf"} 1
+ybclient_rpc_duration_seconds_sum{error_class="",host="127.0.0.1:7100",method="ListTables",outcome="success",service="yb.master.MasterService"} 0.5
+ybclient_rpc_duration_seconds_count{error_class="",host="127.0.0.1:7100",method="ListTables",outcome="success",service="yb.master.MasterService"} 1
+`), "ybclient_rpc_in_flight", "ybclient_rpc_duration_seconds"))
+	})
+
+	t.Run("it=counts reconnects", func(tt *testing.T) {
+		collector := NewCollector(Opts{Namespace: "test"})
+		collector.ClientReconnectAttempt()
+		collector.ClientReconnectAttempt()
+		collector.ClientReconnectFailure()
+		collector.ClientReconnectSuccess()
+		assert.Nil(tt, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP test_reconnects_total Number of reconnect attempts, successes and failures.
+# TYPE test_reconnects_total counter
+test_reconnects_total{result="attempt"} 2
+test_reconnects_total{result="failure"} 1
+test_reconnects_total{result="success"} 1
+`), "test_reconnects_total"))
+	})
+
+}
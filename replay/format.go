@@ -0,0 +1,206 @@
+// Package replay records the RPC traffic of the client and replays it
+// without a running cluster.
+//
+// The Recorder wraps the connections of the client and writes every
+// exchange, the request header and payload and the response header and bytes,
+// to a recording. The Replayer serves the recorded responses back
+// to the client connected with its connector or dialer.
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// FormatVersion is the version of the recording format written by the Writer.
+const FormatVersion uint16 = 1
+
+// formatMagic starts every recording.
+var formatMagic = []byte("YBRR")
+
+// maxFieldSize limits the size of a single recorded field.
+const maxFieldSize = 256 * 1024 * 1024
+
+// Exchange is a single recorded request with its response.
+type Exchange struct {
+	// Address is the address the connection was dialed to.
+	Address string
+	// RequestHeader is the serialized request header.
+	RequestHeader []byte
+	// RequestPayload is the serialized request payload.
+	RequestPayload []byte
+	// ResponseHeader is the serialized response header.
+	ResponseHeader []byte
+	// ResponseBytes are the response frame bytes following the response header:
+	// the payload length varint, the payload and the sidecars.
+	ResponseBytes []byte
+}
+
+// DecodeRequestHeader returns the deserialized request header.
+func (e *Exchange) DecodeRequestHeader() (*ybApi.RequestHeader, error) {
+	header := &ybApi.RequestHeader{}
+	if err := proto.Unmarshal(e.RequestHeader, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// DecodeResponseHeader returns the deserialized response header.
+func (e *Exchange) DecodeResponseHeader() (*ybApi.ResponseHeader, error) {
+	header := &ybApi.ResponseHeader{}
+	if err := proto.Unmarshal(e.ResponseHeader, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// Writer writes exchanges in the recording format:
+// the YBRR magic and a big endian uint16 version, followed by the exchanges.
+// Every exchange field is written as a uvarint length followed by the bytes,
+// in the order of the Exchange fields.
+type Writer struct {
+	writer        io.Writer
+	headerWritten bool
+}
+
+// NewWriter returns a writer writing the recording to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{writer: w}
+}
+
+// Write writes the exchange, the format header is written before the first exchange.
+func (w *Writer) Write(exchange *Exchange) error {
+	if !w.headerWritten {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	buf := []byte{}
+	varint := make([]byte, binary.MaxVarintLen64)
+	for _, field := range [][]byte{
+		[]byte(exchange.Address),
+		exchange.RequestHeader,
+		exchange.RequestPayload,
+		exchange.ResponseHeader,
+		exchange.ResponseBytes,
+	} {
+		buf = append(buf, varint[0:binary.PutUvarint(varint, uint64(len(field)))]...)
+		buf = append(buf, field...)
+	}
+	_, err := w.writer.Write(buf)
+	return err
+}
+
+// Flush writes the format header if no exchange was written yet,
+// so an empty recording is still a valid recording.
+func (w *Writer) Flush() error {
+	if w.headerWritten {
+		return nil
+	}
+	return w.writeHeader()
+}
+
+func (w *Writer) writeHeader() error {
+	header := make([]byte, len(formatMagic)+2)
+	copy(header, formatMagic)
+	binary.BigEndian.PutUint16(header[len(formatMagic):], FormatVersion)
+	if _, err := w.writer.Write(header); err != nil {
+		return err
+	}
+	w.headerWritten = true
+	return nil
+}
+
+// Reader reads exchanges written by the Writer.
+type Reader struct {
+	reader     *bufio.Reader
+	headerRead bool
+}
+
+// NewReader returns a reader reading the recording from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(r)}
+}
+
+// Read returns the next exchange. Returns io.EOF after the last exchange.
+func (r *Reader) Read() (*Exchange, error) {
+	if !r.headerRead {
+		if err := r.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+	fields := make([][]byte, 5)
+	for i := range fields {
+		field, err := r.readField()
+		if err != nil {
+			if err == io.EOF && i > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		fields[i] = field
+	}
+	return &Exchange{
+		Address:        string(fields[0]),
+		RequestHeader:  fields[1],
+		RequestPayload: fields[2],
+		ResponseHeader: fields[3],
+		ResponseBytes:  fields[4],
+	}, nil
+}
+
+// ReadAll returns all remaining exchanges.
+func (r *Reader) ReadAll() ([]*Exchange, error) {
+	exchanges := []*Exchange{}
+	for {
+		exchange, err := r.Read()
+		if err == io.EOF {
+			return exchanges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		exchanges = append(exchanges, exchange)
+	}
+}
+
+func (r *Reader) readHeader() error {
+	header := make([]byte, len(formatMagic)+2)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if string(header[0:len(formatMagic)]) != string(formatMagic) {
+		return fmt.Errorf("not a recording: invalid magic %q", header[0:len(formatMagic)])
+	}
+	if version := binary.BigEndian.Uint16(header[len(formatMagic):]); version != FormatVersion {
+		return fmt.Errorf("unsupported recording format version %d, expected %d", version, FormatVersion)
+	}
+	r.headerRead = true
+	return nil
+}
+
+func (r *Reader) readField() ([]byte, error) {
+	length, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxFieldSize {
+		return nil, fmt.Errorf("recorded field of %d bytes exceeds the maximum size of %d bytes", length, maxFieldSize)
+	}
+	field := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, field); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return field, nil
+}
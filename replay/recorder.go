@@ -0,0 +1,171 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/radekg/yugabyte-db-go-client/client"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// Recorder records the exchanges of the connections dialed with its dialer.
+// The connections are recorded as seen by the client: TLS connections are
+// established over the dialed connection and can't be recorded,
+// record against servers without TLS.
+type Recorder struct {
+	lock   *sync.Mutex
+	writer *Writer
+	err    error
+}
+
+// NewRecorder returns a recorder writing the recording to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		lock:   &sync.Mutex{},
+		writer: NewWriter(w),
+	}
+}
+
+// Dialer returns a dialer recording the connections dialed with the forward dialer.
+// If the forward dialer is nil, the default TCP dialer is used.
+// Use with YBClient.WithDialer, Connector.WithDialer or YBTServerClient.WithDialer.
+func (r *Recorder) Dialer(forward client.Dialer) client.Dialer {
+	if forward == nil {
+		forward = client.NewTCPDialer(configs.DefaultDialTimeout, configs.DefaultKeepAlive)
+	}
+	return client.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := forward.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return &recordingConn{
+			Conn:     conn,
+			address:  address,
+			recorder: r,
+			lock:     &sync.Mutex{},
+			pending:  map[int32]*Exchange{},
+		}, nil
+	})
+}
+
+// Close writes the format header if nothing was recorded
+// and returns the first error of the recording.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err == nil {
+		r.err = r.writer.Flush()
+	}
+	return r.err
+}
+
+// Err returns the first error of the recording, if any.
+func (r *Recorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(exchange *Exchange) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.writer.Write(exchange)
+}
+
+func (r *Recorder) fail(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// recordingConn parses the frames written and read by the client
+// and records the requests paired with their responses by call id.
+type recordingConn struct {
+	net.Conn
+	address  string
+	recorder *Recorder
+
+	lock          *sync.Mutex
+	headerWritten int
+	outgoing      frameParser
+	incoming      frameParser
+	pending       map[int32]*Exchange
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.sent(p[0:n])
+	return n, err
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received(p[0:n])
+	return n, err
+}
+
+func (c *recordingConn) sent(data []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// the connection header precedes the first request:
+	if c.headerWritten < len(wire.ConnectionHeader) {
+		skip := len(wire.ConnectionHeader) - c.headerWritten
+		if skip > len(data) {
+			skip = len(data)
+		}
+		c.headerWritten = c.headerWritten + skip
+		data = data[skip:]
+	}
+	for _, frame := range c.outgoing.feed(data) {
+		headerBytes, payload, err := wire.SplitRequest(frame)
+		if err != nil {
+			c.recorder.fail(err)
+			continue
+		}
+		header := &ybApi.RequestHeader{}
+		if err := proto.Unmarshal(headerBytes, header); err != nil {
+			c.recorder.fail(err)
+			continue
+		}
+		c.pending[header.GetCallId()] = &Exchange{
+			Address:        c.address,
+			RequestHeader:  headerBytes,
+			RequestPayload: payload,
+		}
+	}
+}
+
+func (c *recordingConn) received(data []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, frame := range c.incoming.feed(data) {
+		headerBytes, rest, err := wire.SplitHeader(frame)
+		if err != nil {
+			c.recorder.fail(err)
+			continue
+		}
+		header := &ybApi.ResponseHeader{}
+		if err := proto.Unmarshal(headerBytes, header); err != nil {
+			c.recorder.fail(err)
+			continue
+		}
+		exchange, ok := c.pending[header.GetCallId()]
+		if !ok {
+			continue
+		}
+		delete(c.pending, header.GetCallId())
+		exchange.ResponseHeader = headerBytes
+		exchange.ResponseBytes = rest
+		c.recorder.record(exchange)
+	}
+}
@@ -0,0 +1,271 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/client"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// testMaster starts a master answering GetMasterRegistration with the leader role,
+// GetStatus with the server version and ListTabletServers with a single tablet server.
+// Returns the address and the function stopping the master.
+func testMaster(t *testing.T) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed creating a listener: '%v'", err)
+	}
+	lock := &sync.Mutex{}
+	conns := []net.Conn{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			lock.Lock()
+			conns = append(conns, conn)
+			lock.Unlock()
+			go testServe(t, conn)
+		}
+	}()
+	stop := func() {
+		listener.Close()
+		lock.Lock()
+		defer lock.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	t.Cleanup(stop)
+	return listener.Addr().String(), stop
+}
+
+func testServe(t *testing.T, conn net.Conn) {
+	header := make([]byte, len(wire.ConnectionHeader))
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	for {
+		frame, err := wire.ReadFrame(conn, configs.DefaultMaxFrameSize)
+		if err != nil {
+			return
+		}
+		headerBytes, _, err := wire.SplitRequest(frame)
+		if err != nil {
+			t.Errorf("failed reading request: '%v'", err)
+			return
+		}
+		requestHeader := &ybApi.RequestHeader{}
+		if err := proto.Unmarshal(headerBytes, requestHeader); err != nil {
+			t.Errorf("failed reading request header: '%v'", err)
+			return
+		}
+		var response protoreflect.ProtoMessage
+		switch requestHeader.GetRemoteMethod().GetMethodName() {
+		case "GetMasterRegistration":
+			response = &ybApi.GetMasterRegistrationResponsePB{
+				InstanceId: &ybApi.NodeInstancePB{
+					PermanentUuid: []byte("master-1"),
+					InstanceSeqno: utils.PInt64(0),
+				},
+				Role: ybApi.PeerRole_LEADER.Enum(),
+			}
+		case "GetStatus":
+			response = &ybApi.GetStatusResponsePB{
+				Status: &ybApi.ServerStatusPB{
+					NodeInstance: &ybApi.NodeInstancePB{
+						PermanentUuid: []byte("master-1"),
+						InstanceSeqno: utils.PInt64(0),
+					},
+					VersionInfo: &ybApi.VersionInfoPB{
+						VersionNumber: utils.PString("2.13.0.0"),
+					},
+				},
+			}
+		default:
+			response = &ybApi.ListTabletServersResponsePB{
+				Servers: []*ybApi.ListTabletServersResponsePB_Entry{
+					{
+						InstanceId: &ybApi.NodeInstancePB{
+							PermanentUuid: []byte("tserver-1"),
+							InstanceSeqno: utils.PInt64(0),
+						},
+						Alive: utils.PBool(true),
+					},
+				},
+			}
+		}
+		if err := utils.WriteMessages(conn, &ybApi.ResponseHeader{
+			CallId:  utils.PInt32(requestHeader.GetCallId()),
+			IsError: utils.PBool(false),
+		}, response); err != nil {
+			return
+		}
+	}
+}
+
+func testClient(t *testing.T, address string, dialer client.Dialer) client.YBClient {
+	c := client.NewYBClient(&configs.YBClientConfig{
+		MasterHostPort: []string{address},
+		OpTimeout:      time.Second,
+	}).WithLogger(hclog.NewNullLogger()).WithDialer(dialer)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("expected client to connect but received: '%v'", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c
+}
+
+func TestRecordReplay(t *testing.T) {
+
+	address, stop := testMaster(t)
+
+	recording := bytes.NewBuffer([]byte{})
+	recorder := NewRecorder(recording)
+	recordingClient := testClient(t, address, recorder.Dialer(nil))
+	recorded := &ybApi.ListTabletServersResponsePB{}
+	assert.Nil(t, recordingClient.Execute(&ybApi.ListTabletServersRequestPB{}, recorded))
+	recordingClient.Close()
+	assert.Nil(t, recorder.Close())
+	stop()
+
+	t.Run("it=records every exchange", func(tt *testing.T) {
+		exchanges, err := NewReader(bytes.NewReader(recording.Bytes())).ReadAll()
+		assert.Nil(tt, err)
+		// the server version is detected on connect:
+		if assert.Equal(tt, 3, len(exchanges)) {
+			methods := []string{}
+			for _, exchange := range exchanges {
+				assert.Equal(tt, address, exchange.Address)
+				header, err := exchange.DecodeRequestHeader()
+				assert.Nil(tt, err)
+				methods = append(methods, header.GetRemoteMethod().GetMethodName())
+			}
+			assert.Equal(tt, []string{"GetStatus", "GetMasterRegistration", "ListTabletServers"}, methods)
+		}
+	})
+
+	t.Run("it=replays recorded responses", func(tt *testing.T) {
+		replayer, err := NewReplayer(bytes.NewReader(recording.Bytes()))
+		assert.Nil(tt, err)
+		c := testClient(tt, address, replayer.Dialer())
+		response := &ybApi.ListTabletServersResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListTabletServersRequestPB{}, response))
+		assert.True(tt, proto.Equal(recorded, response))
+		assert.Nil(tt, replayer.Err())
+		assert.Empty(tt, replayer.Unused())
+		// replayed exchanges are repeated:
+		assert.Nil(tt, c.Execute(&ybApi.ListTabletServersRequestPB{}, &ybApi.ListTabletServersResponsePB{}))
+	})
+
+	t.Run("it=reports mismatched requests with a diff", func(tt *testing.T) {
+		replayer, err := NewReplayer(bytes.NewReader(recording.Bytes()))
+		assert.Nil(tt, err)
+		c := testClient(tt, address, replayer.Dialer())
+		err = c.Execute(&ybApi.ListTabletServersRequestPB{PrimaryOnly: utils.PBool(true)}, &ybApi.ListTabletServersResponsePB{})
+		assert.IsType(tt, &clientErrors.ServiceRPCError{}, err)
+		mismatches := replayer.Mismatches()
+		if assert.Equal(tt, 1, len(mismatches)) {
+			assert.Equal(tt, "ListTabletServers", mismatches[0].Method)
+			assert.Contains(tt, mismatches[0].Diff, "+ primary_only:")
+			assert.Contains(tt, err.Error(), "primary_only")
+		}
+		assert.Equal(tt, 1, len(replayer.Unused()))
+	})
+
+	t.Run("it=closes connections sending oversized frames", func(tt *testing.T) {
+		replayer, err := NewReplayer(bytes.NewReader(recording.Bytes()))
+		assert.Nil(tt, err)
+		conn, err := replayer.Dialer().DialContext(context.Background(), "tcp", address)
+		if !assert.Nil(tt, err) {
+			return
+		}
+		defer conn.Close()
+		frameLength := make([]byte, wire.FrameLengthSize)
+		binary.BigEndian.PutUint32(frameLength, configs.DefaultMaxFrameSize+1)
+		_, err = conn.Write(append(append([]byte{}, wire.ConnectionHeader...), frameLength...))
+		assert.Nil(tt, err)
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		_, err = conn.Read(make([]byte, 1))
+		assert.Equal(tt, io.EOF, err)
+	})
+
+	t.Run("it=replays single node clients with the connector", func(tt *testing.T) {
+		replayer, err := NewReplayer(bytes.NewReader(recording.Bytes()))
+		assert.Nil(tt, err)
+		c, err := replayer.Connector().WithLogger(hclog.NewNullLogger()).Connect(&configs.YBSingleNodeClientConfig{
+			MasterHostPort: address,
+			OpTimeout:      1000,
+		})
+		if !assert.Nil(tt, err) {
+			return
+		}
+		defer c.Close()
+		<-c.OnConnected()
+		response := &ybApi.ListTabletServersResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListTabletServersRequestPB{}, response))
+		assert.True(tt, proto.Equal(recorded, response))
+	})
+
+}
+
+func TestFormat(t *testing.T) {
+
+	t.Run("it=round trips exchanges", func(tt *testing.T) {
+		exchanges := []*Exchange{
+			{Address: "a:1", RequestHeader: []byte{1}, RequestPayload: []byte{2, 3}, ResponseHeader: []byte{4}, ResponseBytes: []byte{0}},
+			{Address: "b:2", RequestHeader: []byte{}, RequestPayload: []byte{}, ResponseHeader: []byte{}, ResponseBytes: []byte{}},
+		}
+		buf := bytes.NewBuffer([]byte{})
+		writer := NewWriter(buf)
+		for _, exchange := range exchanges {
+			assert.Nil(tt, writer.Write(exchange))
+		}
+		read, err := NewReader(buf).ReadAll()
+		assert.Nil(tt, err)
+		assert.Equal(tt, exchanges, read)
+	})
+
+	t.Run("it=reads empty recordings", func(tt *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		assert.Nil(tt, NewWriter(buf).Flush())
+		read, err := NewReader(buf).ReadAll()
+		assert.Nil(tt, err)
+		assert.Empty(tt, read)
+	})
+
+	t.Run("it=rejects unknown formats", func(tt *testing.T) {
+		_, err := NewReader(bytes.NewReader([]byte("YBXX\x00\x01"))).Read()
+		assert.Error(tt, err)
+		version := make([]byte, 2)
+		binary.BigEndian.PutUint16(version, FormatVersion+1)
+		_, err = NewReader(bytes.NewReader(append([]byte("YBRR"), version...))).Read()
+		assert.Error(tt, err)
+	})
+
+	t.Run("it=rejects truncated recordings", func(tt *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		assert.Nil(tt, NewWriter(buf).Write(&Exchange{Address: "a:1", RequestPayload: []byte{1, 2, 3}}))
+		_, err := NewReader(bytes.NewReader(buf.Bytes()[0 : buf.Len()-2])).Read()
+		assert.Equal(tt, io.ErrUnexpectedEOF, err)
+	})
+
+}
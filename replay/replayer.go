@@ -0,0 +1,301 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/radekg/yugabyte-db-go-client/client"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// MismatchError is reported when no recorded exchange matches a request.
+type MismatchError struct {
+	Address string
+	Service string
+	Method  string
+	// Diff is the difference between the closest recorded request
+	// of the same method and the received request.
+	// Lines starting with - are recorded, lines starting with + are received.
+	Diff string
+}
+
+func (e *MismatchError) Error() string {
+	if e.Diff == "" {
+		return fmt.Sprintf("replay: no recorded %s.%s request for %s", e.Service, e.Method, e.Address)
+	}
+	return fmt.Sprintf("replay: no recorded %s.%s request for %s matches, closest recorded request differs:\n%s",
+		e.Service, e.Method, e.Address, e.Diff)
+}
+
+// Replayer serves recorded exchanges back to the client.
+// A request matches a recorded exchange when the service, the method
+// and the payload are equal. Exchanges recorded for the dialed address are preferred.
+// Every exchange is replayed once in the recorded order, when all matching
+// exchanges were replayed, the last one is repeated.
+// Requests without a match are answered with an RPC error describing the mismatch.
+type Replayer struct {
+	lock       *sync.Mutex
+	exchanges  []*replayedExchange
+	mismatches []*MismatchError
+}
+
+type replayedExchange struct {
+	*Exchange
+	service        string
+	method         string
+	responseHeader *ybApi.ResponseHeader
+	replayed       bool
+}
+
+// NewReplayer returns a replayer serving the exchanges of the recording read from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	exchanges, err := NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	replayer := &Replayer{
+		lock: &sync.Mutex{},
+	}
+	for _, exchange := range exchanges {
+		requestHeader, err := exchange.DecodeRequestHeader()
+		if err != nil {
+			return nil, fmt.Errorf("invalid recorded request header: %v", err)
+		}
+		responseHeader, err := exchange.DecodeResponseHeader()
+		if err != nil {
+			return nil, fmt.Errorf("invalid recorded response header: %v", err)
+		}
+		replayer.exchanges = append(replayer.exchanges, &replayedExchange{
+			Exchange:       exchange,
+			service:        requestHeader.GetRemoteMethod().GetServiceName(),
+			method:         requestHeader.GetRemoteMethod().GetMethodName(),
+			responseHeader: responseHeader,
+		})
+	}
+	return replayer, nil
+}
+
+// Dialer returns a dialer connecting to the replayer.
+// Use with YBClient.WithDialer, Connector.WithDialer or YBTServerClient.WithDialer.
+func (r *Replayer) Dialer() client.Dialer {
+	return client.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		server, conn := net.Pipe()
+		go r.serve(server, address)
+		return conn, nil
+	})
+}
+
+// Connector returns a connector connecting single node clients to the replayer.
+func (r *Replayer) Connector() client.Connector {
+	return client.NewDefaultConnector().WithDialer(r.Dialer())
+}
+
+// Mismatches returns the requests which did not match any recorded exchange.
+func (r *Replayer) Mismatches() []*MismatchError {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]*MismatchError{}, r.mismatches...)
+}
+
+// Err returns the first mismatch, if any.
+func (r *Replayer) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.mismatches) == 0 {
+		return nil
+	}
+	return r.mismatches[0]
+}
+
+// Unused returns the recorded exchanges which were not replayed.
+func (r *Replayer) Unused() []*Exchange {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	unused := []*Exchange{}
+	for _, exchange := range r.exchanges {
+		if !exchange.replayed {
+			unused = append(unused, exchange.Exchange)
+		}
+	}
+	return unused
+}
+
+func (r *Replayer) serve(conn net.Conn, address string) {
+	defer conn.Close()
+	header := make([]byte, len(wire.ConnectionHeader))
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	for {
+		frame, err := wire.ReadFrame(conn, configs.DefaultMaxFrameSize)
+		if err != nil {
+			return
+		}
+		headerBytes, payload, err := wire.SplitRequest(frame)
+		if err != nil {
+			return
+		}
+		requestHeader := &ybApi.RequestHeader{}
+		if err := proto.Unmarshal(headerBytes, requestHeader); err != nil {
+			return
+		}
+		responseHeader, body := r.respond(address, requestHeader, payload)
+		responseHeaderBytes, err := proto.Marshal(responseHeader)
+		if err != nil {
+			return
+		}
+		if err := wire.WriteFrame(conn, responseHeaderBytes, body); err != nil {
+			return
+		}
+	}
+}
+
+// respond returns the response header and the bytes following it for the request.
+func (r *Replayer) respond(address string, requestHeader *ybApi.RequestHeader, payload []byte) (*ybApi.ResponseHeader, []byte) {
+	service := requestHeader.GetRemoteMethod().GetServiceName()
+	method := requestHeader.GetRemoteMethod().GetMethodName()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if exchange := r.matchUnsafe(address, service, method, payload); exchange != nil {
+		exchange.replayed = true
+		responseHeader := proto.Clone(exchange.responseHeader).(*ybApi.ResponseHeader)
+		responseHeader.CallId = utils.PInt32(requestHeader.GetCallId())
+		return responseHeader, exchange.ResponseBytes
+	}
+
+	mismatch := &MismatchError{
+		Address: address,
+		Service: service,
+		Method:  method,
+		Diff:    r.closestDiffUnsafe(service, method, payload),
+	}
+	r.mismatches = append(r.mismatches, mismatch)
+	errorPayload, _ := proto.Marshal(&ybApi.ErrorStatusPB{
+		Message: utils.PString(mismatch.Error()),
+		Code:    ybApi.ErrorStatusPB_ERROR_INVALID_REQUEST.Enum(),
+	})
+	return &ybApi.ResponseHeader{
+		CallId:  utils.PInt32(requestHeader.GetCallId()),
+		IsError: utils.PBool(true),
+	}, wire.AppendLengthDelimited(nil, errorPayload)
+}
+
+// matchUnsafe returns the exchange to replay for the request, nil if there is none.
+func (r *Replayer) matchUnsafe(address, service, method string, payload []byte) *replayedExchange {
+	var anyAddress, repeated *replayedExchange
+	for _, exchange := range r.exchanges {
+		if exchange.service != service || exchange.method != method || !bytes.Equal(exchange.RequestPayload, payload) {
+			continue
+		}
+		if exchange.replayed {
+			if exchange.Address == address || repeated == nil {
+				repeated = exchange
+			}
+			continue
+		}
+		if exchange.Address == address {
+			return exchange
+		}
+		if anyAddress == nil {
+			anyAddress = exchange
+		}
+	}
+	if anyAddress != nil {
+		return anyAddress
+	}
+	return repeated
+}
+
+// closestDiffUnsafe returns the shortest diff between a recorded request
+// of the method and the payload. Returns an empty string if the method was not recorded.
+func (r *Replayer) closestDiffUnsafe(service, method string, payload []byte) string {
+	received := formatPayload(service, method, payload)
+	closest := ""
+	closestChanges := -1
+	for _, exchange := range r.exchanges {
+		if exchange.service != service || exchange.method != method {
+			continue
+		}
+		diff, changes := diffLines(formatPayload(service, method, exchange.RequestPayload), received)
+		if closestChanges < 0 || changes < closestChanges {
+			closest = diff
+			closestChanges = changes
+		}
+	}
+	return closest
+}
+
+// formatPayload returns the text representation of the request payload,
+// one field per line. Payloads of unknown methods are hex dumped.
+func formatPayload(service, method string, payload []byte) []string {
+	if descriptor, ok := client.LookupInputType(service, method); ok {
+		message := dynamicpb.NewMessage(descriptor)
+		if err := proto.Unmarshal(payload, message); err == nil {
+			text := prototext.MarshalOptions{Multiline: true, Indent: "  "}.Format(message)
+			return splitLines(text)
+		}
+	}
+	return splitLines(hex.Dump(payload))
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffLines returns the line diff of a and b and the number of changed lines.
+func diffLines(a, b []string) (string, int) {
+	// longest common subsequence table:
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	out := &strings.Builder{}
+	changes := 0
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(out, "  %s\n", a[i])
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			fmt.Fprintf(out, "+ %s\n", b[j])
+			j, changes = j+1, changes+1
+		default:
+			fmt.Fprintf(out, "- %s\n", a[i])
+			i, changes = i+1, changes+1
+		}
+	}
+	return out.String(), changes
+}
@@ -0,0 +1,30 @@
+package replay
+
+import (
+	"encoding/binary"
+
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+)
+
+// frameParser splits a byte stream into length-delimited frames.
+type frameParser struct {
+	buf []byte
+}
+
+// feed appends the data to the stream and returns the complete frames,
+// without the length prefix.
+func (p *frameParser) feed(data []byte) [][]byte {
+	p.buf = append(p.buf, data...)
+	frames := [][]byte{}
+	for len(p.buf) >= wire.FrameLengthSize {
+		frameLength := int(binary.BigEndian.Uint32(p.buf))
+		if len(p.buf) < wire.FrameLengthSize+frameLength {
+			break
+		}
+		frame := make([]byte, frameLength)
+		copy(frame, p.buf[wire.FrameLengthSize:])
+		frames = append(frames, frame)
+		p.buf = p.buf[wire.FrameLengthSize+frameLength:]
+	}
+	return frames
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCertificateAuthority is a certificate authority issuing certificates in tests.
+type TestCertificateAuthority struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// NewTestCertificateAuthority creates a new self-signed certificate authority.
+func NewTestCertificateAuthority(t *testing.T) *TestCertificateAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating CA key: '%v'", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed creating CA certificate: '%v'", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed parsing CA certificate: '%v'", err)
+	}
+	return &TestCertificateAuthority{
+		cert:    cert,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:     key,
+	}
+}
+
+// CertPEM returns the PEM encoded CA certificate.
+func (ca *TestCertificateAuthority) CertPEM() []byte {
+	return ca.certPEM
+}
+
+// WriteCert writes the PEM encoded CA certificate to a file in the directory
+// and returns the file path.
+func (ca *TestCertificateAuthority) WriteCert(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(path, ca.certPEM, 0600); err != nil {
+		t.Fatalf("failed writing CA certificate: '%v'", err)
+	}
+	return path
+}
+
+// Issue issues a certificate for the common name, valid for the hosts.
+// Hosts may be IP addresses or DNS names. Returns the PEM encoded certificate and key.
+func (ca *TestCertificateAuthority) Issue(t *testing.T, commonName string, hosts ...string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed generating key: '%v'", err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("failed generating serial number: '%v'", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed creating certificate: '%v'", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed marshalling key: '%v'", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// IssueTLS issues a certificate for the common name, valid for the hosts,
+// and returns it as a TLS certificate.
+func (ca *TestCertificateAuthority) IssueTLS(t *testing.T, commonName string, hosts ...string) tls.Certificate {
+	certPEM, keyPEM := ca.Issue(t, commonName, hosts...)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed loading key pair: '%v'", err)
+	}
+	return cert
+}
+
+// IssueFiles issues a certificate for the common name, valid for the hosts,
+// writes the certificate and the key to files in the directory and returns the file paths.
+func (ca *TestCertificateAuthority) IssueFiles(t *testing.T, dir, commonName string, hosts ...string) (string, string) {
+	certPEM, keyPEM := ca.Issue(t, commonName, hosts...)
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatalf("failed writing certificate: '%v'", err)
+	}
+	if err := ioutil.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatalf("failed writing key: '%v'", err)
+	}
+	return certPath, keyPath
+}
@@ -0,0 +1,177 @@
+package fakeserver
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/client"
+	"github.com/radekg/yugabyte-db-go-client/configs"
+	clientErrors "github.com/radekg/yugabyte-db-go-client/errors"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConnect(t *testing.T, server *Server) client.YBConnectedClient {
+	c, err := client.NewDefaultConnector().
+		WithDialer(server.Dialer()).
+		WithLogger(hclog.NewNullLogger()).
+		Connect(&configs.YBSingleNodeClientConfig{
+			MasterHostPort: "fake:7100",
+			OpTimeout:      1000,
+		})
+	if err != nil {
+		t.Fatalf("expected client to connect but received: '%v'", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	<-c.OnConnected()
+	return c
+}
+
+func TestServer(t *testing.T) {
+
+	server := NewServer().
+		Handle(MasterService, "IsLoadBalanced", func(_ context.Context, request *Request) (*Response, error) {
+			payload := &ybApi.IsLoadBalancedRequestPB{}
+			if err := request.Unmarshal(payload); err != nil {
+				return nil, err
+			}
+			if payload.GetExpectedNumServers() < 0 {
+				return nil, &RPCError{
+					Code:    ybApi.ErrorStatusPB_ERROR_INVALID_REQUEST,
+					Message: "negative number of servers",
+				}
+			}
+			return Respond(&ybApi.IsLoadBalancedResponsePB{}, []byte("first"), []byte("second")), nil
+		})
+	defer server.Close()
+
+	c := testConnect(t, server)
+
+	t.Run("it=dispatches requests to handlers", func(tt *testing.T) {
+		response := &ybApi.IsLoadBalancedResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.IsLoadBalancedRequestPB{ExpectedNumServers: utils.PInt32(3)}, response))
+		assert.Nil(tt, response.Error)
+	})
+
+	t.Run("it=responds with error statuses", func(tt *testing.T) {
+		err := c.Execute(&ybApi.IsLoadBalancedRequestPB{ExpectedNumServers: utils.PInt32(-1)}, &ybApi.IsLoadBalancedResponsePB{})
+		if assert.IsType(tt, &clientErrors.ServiceRPCError{}, err) {
+			status := err.(*clientErrors.ServiceRPCError).Cause
+			assert.Equal(tt, ybApi.ErrorStatusPB_ERROR_INVALID_REQUEST, status.GetCode())
+			assert.Equal(tt, "negative number of servers", status.GetMessage())
+		}
+	})
+
+	t.Run("it=responds to unknown methods with no such method", func(tt *testing.T) {
+		err := c.Execute(&ybApi.ListMastersRequestPB{}, &ybApi.ListMastersResponsePB{})
+		if assert.IsType(tt, &clientErrors.ServiceRPCError{}, err) {
+			assert.Equal(tt, ybApi.ErrorStatusPB_ERROR_NO_SUCH_METHOD, err.(*clientErrors.ServiceRPCError).Cause.GetCode())
+		}
+	})
+
+	t.Run("it=writes sidecars after the response message", func(tt *testing.T) {
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+		server.ServeConn(serverConn)
+
+		request := bytes.NewBuffer(append([]byte{}, wire.ConnectionHeader...))
+		assert.Nil(tt, utils.WriteMessages(request, &ybApi.RequestHeader{
+			CallId: utils.PInt32(7),
+			RemoteMethod: &ybApi.RemoteMethodPB{
+				ServiceName: utils.PString(MasterService),
+				MethodName:  utils.PString("IsLoadBalanced"),
+			},
+		}, &ybApi.IsLoadBalancedRequestPB{}))
+		go clientConn.Write(request.Bytes())
+
+		clientConn.SetReadDeadline(time.Now().Add(time.Second))
+		frame, err := wire.ReadFrame(clientConn, maxFrameSize)
+		if !assert.Nil(tt, err) {
+			return
+		}
+		reader := bytes.NewBuffer(frame)
+		headerLength, _ := utils.ReadUvarint32(reader)
+		header := &ybApi.ResponseHeader{}
+		assert.Nil(tt, utils.DeserializeProto(reader.Next(int(headerLength)), header))
+		assert.Equal(tt, int32(7), header.GetCallId())
+		assert.False(tt, header.GetIsError())
+		// sidecar offsets are counted from the first byte of the response message:
+		utils.ReadUvarint32(reader)
+		body := reader.Bytes()
+		offsets := header.GetSidecarOffsets()
+		if assert.Equal(tt, 2, len(offsets)) {
+			assert.Equal(tt, "first", string(body[offsets[0]:offsets[1]]))
+			assert.Equal(tt, "second", string(body[offsets[1]:]))
+			assert.Nil(tt, utils.DeserializeProto(body[0:offsets[0]], &ybApi.IsLoadBalancedResponsePB{}))
+		}
+	})
+
+}
+
+func TestMasterCluster(t *testing.T) {
+
+	cluster := StartMasters(t, 3)
+	cluster.AddTabletServer("tserver-1", "127.0.0.1:9100")
+
+	c := client.NewYBClient(&configs.YBClientConfig{
+		MasterHostPort: cluster.Addresses(),
+		OpTimeout:      time.Second,
+		RetryInterval:  time.Millisecond,
+	}).WithLogger(hclog.NewNullLogger())
+	if err := c.Connect(); err != nil {
+		t.Fatalf("expected client to connect but received: '%v'", err)
+	}
+	defer c.Close()
+
+	t.Run("it=lists masters with roles", func(tt *testing.T) {
+		response := &ybApi.ListMastersResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListMastersRequestPB{}, response))
+		roles := []ybApi.PeerRole{}
+		for _, master := range response.GetMasters() {
+			roles = append(roles, master.GetRole())
+		}
+		assert.Equal(tt, []ybApi.PeerRole{ybApi.PeerRole_LEADER, ybApi.PeerRole_FOLLOWER, ybApi.PeerRole_FOLLOWER}, roles)
+	})
+
+	t.Run("it=reports the server version", func(tt *testing.T) {
+		version, ok := c.ServerVersion()
+		if assert.True(tt, ok) {
+			assert.Equal(tt, "2.13.0.0", version.String())
+		}
+	})
+
+	t.Run("it=lists tablet servers", func(tt *testing.T) {
+		response := &ybApi.ListTabletServersResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListTabletServersRequestPB{}, response))
+		if assert.Equal(tt, 1, len(response.GetServers())) {
+			assert.Equal(tt, "tserver-1", string(response.GetServers()[0].GetInstanceId().GetPermanentUuid()))
+		}
+	})
+
+	t.Run("it=follows the leader failover", func(tt *testing.T) {
+		cluster.SetLeader(2)
+		assert.Equal(tt, cluster.Masters()[2], cluster.Leader())
+		response := &ybApi.ListTabletServersResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListTabletServersRequestPB{}, response))
+		assert.Nil(tt, response.Error)
+		assert.Equal(tt, 1, len(response.GetServers()))
+	})
+
+	t.Run("it=follows a crashed leader", func(tt *testing.T) {
+		previous := cluster.Leader()
+		cluster.SetLeader(1)
+		previous.CloseConnections()
+		response := &ybApi.ListTabletServersResponsePB{}
+		assert.Nil(tt, c.Execute(&ybApi.ListTabletServersRequestPB{}, response))
+		assert.Nil(tt, response.Error)
+	})
+
+}
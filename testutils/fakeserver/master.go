@@ -0,0 +1,263 @@
+package fakeserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+)
+
+// MasterService is the service name of the master RPC calls.
+const MasterService = "yb.master.MasterService"
+
+// GenericService is the service name of the RPC calls served by all servers.
+const GenericService = "yb.server.GenericService"
+
+// ServerVersion is the version reported by the fake masters.
+const ServerVersion = "2.13.0.0"
+
+// GetMasterRegistrationHandler returns a handler responding to GetMasterRegistration
+// with the master uuid and the role returned by the role function.
+func GetMasterRegistrationHandler(uuid string, role func() ybApi.PeerRole) Handler {
+	return func(_ context.Context, _ *Request) (*Response, error) {
+		return Respond(&ybApi.GetMasterRegistrationResponsePB{
+			InstanceId: &ybApi.NodeInstancePB{
+				PermanentUuid: []byte(uuid),
+				InstanceSeqno: utils.PInt64(0),
+			},
+			Role: role().Enum(),
+		}), nil
+	}
+}
+
+// GetStatusHandler returns a handler responding to GetStatus
+// with the server uuid and the version.
+func GetStatusHandler(uuid, version string) Handler {
+	return func(_ context.Context, _ *Request) (*Response, error) {
+		return Respond(&ybApi.GetStatusResponsePB{
+			Status: &ybApi.ServerStatusPB{
+				NodeInstance: &ybApi.NodeInstancePB{
+					PermanentUuid: []byte(uuid),
+					InstanceSeqno: utils.PInt64(0),
+				},
+				VersionInfo: &ybApi.VersionInfoPB{
+					VersionNumber: utils.PString(version),
+				},
+			},
+		}), nil
+	}
+}
+
+// ListMastersHandler returns a handler responding to ListMasters
+// with the masters returned by the masters function.
+func ListMastersHandler(masters func() []*ybApi.ServerEntryPB) Handler {
+	return func(_ context.Context, _ *Request) (*Response, error) {
+		return Respond(&ybApi.ListMastersResponsePB{
+			Masters: masters(),
+		}), nil
+	}
+}
+
+// ListTabletServersHandler returns a handler responding to ListTabletServers
+// with the tablet servers returned by the servers function.
+// If the isLeader function returns false, the handler responds with NOT_THE_LEADER.
+func ListTabletServersHandler(isLeader func() bool, servers func() []*ybApi.ListTabletServersResponsePB_Entry) Handler {
+	return func(_ context.Context, request *Request) (*Response, error) {
+		if !isLeader() {
+			return Respond(&ybApi.ListTabletServersResponsePB{
+				Error: NotTheLeader(),
+			}), nil
+		}
+		if err := request.Unmarshal(&ybApi.ListTabletServersRequestPB{}); err != nil {
+			return nil, &RPCError{
+				Code:    ybApi.ErrorStatusPB_ERROR_INVALID_REQUEST,
+				Message: err.Error(),
+			}
+		}
+		return Respond(&ybApi.ListTabletServersResponsePB{
+			Servers: servers(),
+		}), nil
+	}
+}
+
+// NotTheLeader returns the master error sent by a master which is not the leader.
+func NotTheLeader() *ybApi.MasterErrorPB {
+	return &ybApi.MasterErrorPB{
+		Code: ybApi.MasterErrorPB_NOT_THE_LEADER.Enum(),
+		Status: &ybApi.AppStatusPB{
+			Code:    ybApi.AppStatusPB_ILLEGAL_STATE.Enum(),
+			Message: utils.PString("Not the leader"),
+		},
+	}
+}
+
+// Master is a fake master in a fake master cluster.
+type Master struct {
+	*Server
+	cluster *MasterCluster
+	uuid    string
+}
+
+// UUID returns the permanent uuid of the master.
+func (m *Master) UUID() string {
+	return m.uuid
+}
+
+// Role returns the current role of the master.
+func (m *Master) Role() ybApi.PeerRole {
+	m.cluster.lock.Lock()
+	defer m.cluster.lock.Unlock()
+	return m.roleUnsafe()
+}
+
+func (m *Master) roleUnsafe() ybApi.PeerRole {
+	if m.cluster.masters[m.cluster.leader] == m {
+		return ybApi.PeerRole_LEADER
+	}
+	return ybApi.PeerRole_FOLLOWER
+}
+
+// MasterCluster is a set of fake masters listening on local ports.
+// A single master is the leader, all masters serve GetMasterRegistration,
+// GetStatus, ListMasters and ListTabletServers. Followers respond to ListTabletServers
+// with NOT_THE_LEADER. Additional handlers can be registered on every master.
+type MasterCluster struct {
+	lock     *sync.Mutex
+	masters  []*Master
+	leader   int
+	tservers []*ybApi.ListTabletServersResponsePB_Entry
+}
+
+// StartMasters starts the number of fake masters, the first master is the leader.
+// The masters are closed when the test finishes.
+func StartMasters(t *testing.T, count int) *MasterCluster {
+	cluster := &MasterCluster{
+		lock: &sync.Mutex{},
+	}
+	for i := 0; i < count; i = i + 1 {
+		master := &Master{
+			Server:  NewServer(),
+			cluster: cluster,
+			uuid:    fmt.Sprintf("fake-master-%d", i),
+		}
+		master.Handle(MasterService, "GetMasterRegistration", GetMasterRegistrationHandler(master.uuid, master.Role)).
+			Handle(GenericService, "GetStatus", GetStatusHandler(master.uuid, ServerVersion)).
+			Handle(MasterService, "ListMasters", ListMastersHandler(cluster.serverEntries)).
+			Handle(MasterService, "ListTabletServers", ListTabletServersHandler(func() bool {
+				return master.Role() == ybApi.PeerRole_LEADER
+			}, cluster.TabletServers))
+		cluster.masters = append(cluster.masters, master)
+	}
+	t.Cleanup(cluster.Close)
+	// start serving only when the cluster is complete:
+	for _, master := range cluster.masters {
+		if err := master.Start(); err != nil {
+			t.Fatalf("failed starting fake master: '%v'", err)
+		}
+	}
+	return cluster
+}
+
+// Addresses returns the addresses of the masters.
+func (c *MasterCluster) Addresses() []string {
+	addresses := []string{}
+	for _, master := range c.masters {
+		addresses = append(addresses, master.Address())
+	}
+	return addresses
+}
+
+// Masters returns the masters.
+func (c *MasterCluster) Masters() []*Master {
+	return append([]*Master{}, c.masters...)
+}
+
+// Leader returns the current leader.
+func (c *MasterCluster) Leader() *Master {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.masters[c.leader]
+}
+
+// SetLeader makes the master at the index the leader, other masters become followers.
+// Connections to the previous leader are kept, the clients learn about the new leader
+// from the NOT_THE_LEADER responses. Call CloseConnections on the previous leader
+// to simulate a crashed leader.
+func (c *MasterCluster) SetLeader(index int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.leader = index
+}
+
+// AddTabletServer adds a live tablet server with the uuid and the address
+// to the ListTabletServers responses.
+func (c *MasterCluster) AddTabletServer(uuid, address string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.tservers = append(c.tservers, &ybApi.ListTabletServersResponsePB_Entry{
+		InstanceId: &ybApi.NodeInstancePB{
+			PermanentUuid: []byte(uuid),
+			InstanceSeqno: utils.PInt64(0),
+		},
+		Registration: &ybApi.TSRegistrationPB{
+			Common: &ybApi.ServerRegistrationPB{
+				PrivateRpcAddresses: []*ybApi.HostPortPB{hostPortPB(address)},
+			},
+		},
+		Alive: utils.PBool(true),
+	})
+}
+
+// SetTabletServers replaces the tablet servers of the ListTabletServers responses.
+func (c *MasterCluster) SetTabletServers(servers ...*ybApi.ListTabletServersResponsePB_Entry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.tservers = servers
+}
+
+// TabletServers returns the tablet servers of the ListTabletServers responses.
+func (c *MasterCluster) TabletServers() []*ybApi.ListTabletServersResponsePB_Entry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*ybApi.ListTabletServersResponsePB_Entry{}, c.tservers...)
+}
+
+// Close closes all masters.
+func (c *MasterCluster) Close() {
+	for _, master := range c.masters {
+		master.Close()
+	}
+}
+
+func (c *MasterCluster) serverEntries() []*ybApi.ServerEntryPB {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entries := []*ybApi.ServerEntryPB{}
+	for _, master := range c.masters {
+		entries = append(entries, &ybApi.ServerEntryPB{
+			InstanceId: &ybApi.NodeInstancePB{
+				PermanentUuid: []byte(master.uuid),
+				InstanceSeqno: utils.PInt64(0),
+			},
+			Registration: &ybApi.ServerRegistrationPB{
+				PrivateRpcAddresses: []*ybApi.HostPortPB{hostPortPB(master.Address())},
+			},
+			Role: master.roleUnsafe().Enum(),
+		})
+	}
+	return entries
+}
+
+func hostPortPB(address string) *ybApi.HostPortPB {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return &ybApi.HostPortPB{Host: utils.PString(address), Port: utils.PUint32(0)}
+	}
+	portNumber, _ := strconv.ParseUint(port, 10, 32)
+	return &ybApi.HostPortPB{Host: utils.PString(host), Port: utils.PUint32(uint32(portNumber))}
+}
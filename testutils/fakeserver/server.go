@@ -0,0 +1,348 @@
+// Package fakeserver provides an in-process server speaking the YugabyteDB RPC
+// wire protocol. Requests are dispatched by the remote method to registered handlers.
+// Use it to test code built on the client without running YugabyteDB in Docker.
+package fakeserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/radekg/yugabyte-db-go-client/client"
+	"github.com/radekg/yugabyte-db-go-client/internal/wire"
+	"github.com/radekg/yugabyte-db-go-client/utils"
+	ybApi "github.com/radekg/yugabyte-db-go-proto/v2/yb/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxFrameSize limits the size of the request frame.
+const maxFrameSize = 64 * 1024 * 1024
+
+// Request is a request received by the server.
+type Request struct {
+	Header  *ybApi.RequestHeader
+	Payload []byte
+}
+
+// Service returns the service name of the request.
+func (r *Request) Service() string {
+	return r.Header.GetRemoteMethod().GetServiceName()
+}
+
+// Method returns the method name of the request.
+func (r *Request) Method() string {
+	return r.Header.GetRemoteMethod().GetMethodName()
+}
+
+// Unmarshal deserializes the request payload into the message.
+func (r *Request) Unmarshal(m protoreflect.ProtoMessage) error {
+	return utils.DeserializeProto(r.Payload, m)
+}
+
+// Response is the response to a request.
+type Response struct {
+	Message protoreflect.ProtoMessage
+	// Sidecars are written after the response message and included in the message length.
+	// Sidecar offsets in the response header are counted from the first byte
+	// of the serialized response message.
+	Sidecars [][]byte
+}
+
+// Respond returns a response with the message and the sidecars.
+func Respond(m protoreflect.ProtoMessage, sidecars ...[]byte) *Response {
+	return &Response{Message: m, Sidecars: sidecars}
+}
+
+// RPCError is returned by a handler to respond with an RPC error status.
+type RPCError struct {
+	Code    ybApi.ErrorStatusPB_RpcErrorCodePB
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error: %s: %s", e.Code.String(), e.Message)
+}
+
+// Handler handles a request. The context is canceled when the connection is closed.
+// A returned *RPCError is sent with its code, other errors are sent as ERROR_APPLICATION.
+type Handler func(ctx context.Context, request *Request) (*Response, error)
+
+// Server is an in-process YugabyteDB RPC server.
+type Server struct {
+	lock     *sync.Mutex
+	handlers map[string]Handler
+	listener net.Listener
+	conns    map[net.Conn]context.CancelFunc
+	closed   bool
+	logger   hclog.Logger
+	wg       *sync.WaitGroup
+}
+
+// NewServer returns a server without handlers.
+// Requests for methods without a handler are answered with ERROR_NO_SUCH_METHOD.
+func NewServer() *Server {
+	return &Server{
+		lock:     &sync.Mutex{},
+		handlers: map[string]Handler{},
+		conns:    map[net.Conn]context.CancelFunc{},
+		logger:   hclog.NewNullLogger(),
+		wg:       &sync.WaitGroup{},
+	}
+}
+
+// WithLogger configures the logger for the server.
+func (s *Server) WithLogger(logger hclog.Logger) *Server {
+	if logger != nil {
+		s.logger = logger
+	}
+	return s
+}
+
+// Handle registers the handler for the service method, replacing any previous handler.
+func (s *Server) Handle(service, method string, handler Handler) *Server {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers[service+"."+method] = handler
+	return s
+}
+
+// Start starts listening on a random local port.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves connections accepted by the listener in the background.
+func (s *Server) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		listener.Close()
+		return fmt.Errorf("server closed")
+	}
+	if s.listener != nil {
+		listener.Close()
+		return fmt.Errorf("server already serving on %s", s.listener.Addr().String())
+	}
+	s.listener = listener
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.ServeConn(conn)
+		}
+	}()
+	return nil
+}
+
+// Address returns the address the server listens on,
+// an empty string if the server is not listening.
+func (s *Server) Address() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Dialer returns a dialer connecting to the server in memory, regardless of the address.
+func (s *Server) Dialer() client.Dialer {
+	return client.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		serverConn, clientConn := net.Pipe()
+		s.ServeConn(serverConn)
+		return clientConn, nil
+	})
+}
+
+// ServeConn serves the connection in the background.
+func (s *Server) ServeConn(conn net.Conn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		conn.Close()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.conns[conn] = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.serve(ctx, conn)
+		s.closeConn(conn)
+	}()
+}
+
+// CloseConnections closes all open connections, the server keeps accepting new connections.
+// Use to simulate a broken connection or a restarted server.
+func (s *Server) CloseConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for conn, cancel := range s.conns {
+		cancel()
+		conn.Close()
+	}
+}
+
+// Close stops the listener, closes all connections and waits for the handlers to finish.
+func (s *Server) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn, cancel := range s.conns {
+		cancel()
+		conn.Close()
+	}
+	s.lock.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) closeConn(conn net.Conn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if cancel, ok := s.conns[conn]; ok {
+		cancel()
+		conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+func (s *Server) handler(service, method string) (Handler, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	handler, ok := s.handlers[service+"."+method]
+	return handler, ok
+}
+
+func (s *Server) serve(ctx context.Context, conn net.Conn) {
+	header := make([]byte, len(wire.ConnectionHeader))
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	if !bytes.Equal(header, wire.ConnectionHeader) {
+		s.logger.Error("invalid connection header", "header", header)
+		return
+	}
+	writeLock := &sync.Mutex{}
+	for {
+		request, err := readRequest(conn)
+		if err != nil {
+			if err != io.EOF {
+				s.logger.Debug("connection closed", "reason", err)
+			}
+			return
+		}
+		s.logger.Trace("request", "service", request.Service(), "method", request.Method(), "call-id", request.Header.GetCallId())
+		// requests are handled concurrently, responses may be sent out of order,
+		// the serving goroutine holds the wait group so adding here is safe:
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			header, body := s.handle(ctx, request)
+			writeLock.Lock()
+			defer writeLock.Unlock()
+			if err := writeResponse(conn, header, body); err != nil {
+				s.logger.Debug("failed writing response", "reason", err)
+			}
+		}()
+	}
+}
+
+// handle returns the response header and the response bytes following the header.
+func (s *Server) handle(ctx context.Context, request *Request) (*ybApi.ResponseHeader, []byte) {
+	handler, ok := s.handler(request.Service(), request.Method())
+	if !ok {
+		return errorResponse(request, &RPCError{
+			Code:    ybApi.ErrorStatusPB_ERROR_NO_SUCH_METHOD,
+			Message: fmt.Sprintf("no handler for %s.%s", request.Service(), request.Method()),
+		})
+	}
+	response, err := handler(ctx, request)
+	if err != nil {
+		return errorResponse(request, err)
+	}
+	if response == nil || response.Message == nil {
+		return errorResponse(request, fmt.Errorf("handler for %s.%s returned no response", request.Service(), request.Method()))
+	}
+	payload, err := proto.Marshal(response.Message)
+	if err != nil {
+		return errorResponse(request, err)
+	}
+	header := &ybApi.ResponseHeader{
+		CallId:  utils.PInt32(request.Header.GetCallId()),
+		IsError: utils.PBool(false),
+	}
+	// the message length includes the sidecars following the message:
+	message := payload
+	for _, sidecar := range response.Sidecars {
+		header.SidecarOffsets = append(header.SidecarOffsets, uint32(len(message)))
+		message = append(message, sidecar...)
+	}
+	return header, wire.AppendLengthDelimited(nil, message)
+}
+
+func errorResponse(request *Request, err error) (*ybApi.ResponseHeader, []byte) {
+	status := &ybApi.ErrorStatusPB{
+		Message: utils.PString(err.Error()),
+		Code:    ybApi.ErrorStatusPB_ERROR_APPLICATION.Enum(),
+	}
+	if rpcError, ok := err.(*RPCError); ok {
+		status.Message = utils.PString(rpcError.Message)
+		status.Code = rpcError.Code.Enum()
+	}
+	payload, _ := proto.Marshal(status)
+	return &ybApi.ResponseHeader{
+		CallId:  utils.PInt32(request.Header.GetCallId()),
+		IsError: utils.PBool(true),
+	}, wire.AppendLengthDelimited(nil, payload)
+}
+
+func readRequest(reader io.Reader) (*Request, error) {
+	frame, err := wire.ReadFrame(reader, maxFrameSize)
+	if err != nil {
+		return nil, err
+	}
+	headerBytes, payload, err := wire.SplitRequest(frame)
+	if err != nil {
+		return nil, err
+	}
+	header := &ybApi.RequestHeader{}
+	if err := utils.DeserializeProto(headerBytes, header); err != nil {
+		return nil, err
+	}
+	return &Request{
+		Header:  header,
+		Payload: payload,
+	}, nil
+}
+
+func writeResponse(writer io.Writer, header *ybApi.ResponseHeader, body []byte) error {
+	headerBytes, err := proto.Marshal(header)
+	if err != nil {
+		return err
+	}
+	return wire.WriteFrame(writer, headerBytes, body)
+}
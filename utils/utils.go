@@ -103,6 +103,11 @@ func PInt32(a int32) *int32 {
 	return &a
 }
 
+// PInt64 returns a pointer to an int64.
+func PInt64(a int64) *int64 {
+	return &a
+}
+
 // PUint32 returns a pointer to an uint32.
 func PUint32(a uint32) *uint32 {
 	return &a